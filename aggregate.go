@@ -0,0 +1,239 @@
+package mgots
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// Aggregator identifies the function used to reduce the numeric values in
+// each bucket of an aggregated range into a single value.
+type Aggregator int
+
+const (
+	AggregateAvg Aggregator = iota
+	AggregateSum
+	AggregateMin
+	AggregateMax
+	AggregateCount
+	AggregateFirst
+	AggregateLast
+)
+
+var aggregatorNames = map[Aggregator]string{
+	AggregateAvg:   "avg",
+	AggregateSum:   "sum",
+	AggregateMin:   "min",
+	AggregateMax:   "max",
+	AggregateCount: "count",
+	AggregateFirst: "first",
+	AggregateLast:  "last",
+}
+
+// Errors
+var ErrInvalidInterval = errors.New("Aggregation interval must be greater than zero")
+var ErrUnknownAggregator = errors.New("Unknown aggregation function")
+
+// ParseAggregator returns the Aggregator with the given name (E.g. "avg").
+func ParseAggregator(name string) (Aggregator, error) {
+	name = strings.ToLower(name)
+	for a, n := range aggregatorNames {
+		if n == name {
+			return a, nil
+		}
+	}
+
+	return 0, ErrUnknownAggregator
+}
+
+func (c Aggregator) String() string {
+	if name, ok := aggregatorNames[c]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// bucket accumulates the values of all data points which fall within a
+// single aggregation interval.
+type bucket struct {
+	start time.Time
+	count int
+	sum   float64
+	min   float64
+	max   float64
+	first float64
+	last  float64
+}
+
+// summary is the stored form of a bucket, used by page headers and rollups.
+type summary struct {
+	Count int
+	Sum   float64
+	Min   float64
+	Max   float64
+	First float64
+	Last  float64
+}
+
+func (c *summary) bucket() bucket {
+	return bucket{
+		count: c.Count,
+		sum:   c.Sum,
+		min:   c.Min,
+		max:   c.Max,
+		first: c.First,
+		last:  c.Last,
+	}
+}
+
+func (c *bucket) summary() summary {
+	return summary{
+		Count: c.count,
+		Sum:   c.sum,
+		Min:   c.min,
+		Max:   c.max,
+		First: c.first,
+		Last:  c.last,
+	}
+}
+
+func (c *bucket) add(v float64) {
+	if c.count == 0 {
+		c.min = v
+		c.max = v
+		c.first = v
+	}
+
+	c.count++
+	c.sum += v
+	c.min = math.Min(c.min, v)
+	c.max = math.Max(c.max, v)
+	c.last = v
+}
+
+// merge adds the values accumulated by another bucket, which must follow
+// all values already in this bucket.
+func (c *bucket) merge(b bucket) {
+	if b.count == 0 {
+		return
+	}
+
+	if c.count == 0 {
+		c.min = b.min
+		c.max = b.max
+		c.first = b.first
+	}
+
+	c.count += b.count
+	c.sum += b.sum
+	c.min = math.Min(c.min, b.min)
+	c.max = math.Max(c.max, b.max)
+	c.last = b.last
+}
+
+func (c *bucket) value(aggregator Aggregator) float64 {
+	switch aggregator {
+	case AggregateSum:
+		return c.sum
+	case AggregateMin:
+		return c.min
+	case AggregateMax:
+		return c.max
+	case AggregateCount:
+		return float64(c.count)
+	case AggregateFirst:
+		return c.first
+	case AggregateLast:
+		return c.last
+	}
+
+	return c.sum / float64(c.count)
+}
+
+// aggregation reduces chronologically ordered values, or buckets of values,
+// into one data point per bucket. Empty buckets are omitted.
+type aggregation struct {
+	buckets    bucketing
+	aggregator Aggregator
+	results    DataPoints
+	current    *bucket
+}
+
+// newAggregation returns an aggregation into buckets of the given interval,
+// aligned to multiples of the interval since the zero time.
+func newAggregation(interval time.Duration, aggregator Aggregator) (*aggregation, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	return newBucketAggregation(fixedBuckets(interval), aggregator)
+}
+
+func newBucketAggregation(b bucketing, aggregator Aggregator) (*aggregation, error) {
+	if _, ok := aggregatorNames[aggregator]; !ok {
+		return nil, ErrUnknownAggregator
+	}
+
+	return &aggregation{
+		buckets:    b,
+		aggregator: aggregator,
+		results:    make(DataPoints, 0),
+	}, nil
+}
+
+// add adds a single value at the given timestamp. NaN (null) values are
+// ignored, as in page summaries.
+func (c *aggregation) add(timestamp time.Time, v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+
+	c.merge(timestamp, bucket{count: 1, sum: v, min: v, max: v, first: v, last: v})
+}
+
+// merge adds a bucket of values which all fall within the same bucket as the
+// given timestamp.
+func (c *aggregation) merge(timestamp time.Time, b bucket) {
+	start := c.buckets.start(timestamp)
+	if c.current == nil || !c.current.start.Equal(start) {
+		c.flush()
+		c.current = &bucket{start: start}
+	}
+
+	c.current.merge(b)
+}
+
+func (c *aggregation) flush() {
+	if c.current != nil && c.current.count > 0 {
+		c.results = append(c.results, newFloat64DataPoint(c.current.start, c.current.value(c.aggregator)))
+	}
+	c.current = nil
+}
+
+// finish returns one data point for each interval.
+func (c *aggregation) finish() DataPoints {
+	c.flush()
+	return c.results
+}
+
+// aggregate reduces the given chronologically ordered data points into one
+// data point per interval.
+func aggregate(points DataPoints, interval time.Duration, aggregator Aggregator) (DataPoints, error) {
+	a, err := newAggregation(interval, aggregator)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, point := range points {
+		v, err := point.Float64()
+		if err != nil {
+			return nil, err
+		}
+
+		a.add(point.Timestamp(), v)
+	}
+
+	return a.finish(), nil
+}
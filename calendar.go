@@ -0,0 +1,172 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+	"time"
+)
+
+// Calendar identifies a calendar period used to bucket a range, aligned to
+// local midnight in a given location.
+type Calendar int
+
+const (
+	CalendarDay     Calendar = iota // Buckets begin at midnight each day
+	CalendarWeek                    // Buckets begin at midnight each Monday
+	CalendarMonth                   // Buckets begin at midnight on the first day of each month
+	CalendarQuarter                 // Buckets begin at midnight on the first day of January, April, July and October
+)
+
+var calendarNames = map[Calendar]string{
+	CalendarDay:     "day",
+	CalendarWeek:    "week",
+	CalendarMonth:   "month",
+	CalendarQuarter: "quarter",
+}
+
+// Errors
+var ErrUnknownCalendar = errors.New("Unknown calendar period")
+
+// ParseCalendar returns the Calendar with the given name (E.g. "month").
+func ParseCalendar(name string) (Calendar, error) {
+	name = strings.ToLower(name)
+	for c, n := range calendarNames {
+		if n == name {
+			return c, nil
+		}
+	}
+
+	return 0, ErrUnknownCalendar
+}
+
+func (c Calendar) String() string {
+	if name, ok := calendarNames[c]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// bucketing divides time into consecutive buckets.
+type bucketing interface {
+	// start returns the start of the bucket containing the given time.
+	start(t time.Time) time.Time
+
+	// next returns the start of the bucket following the bucket which starts
+	// at the given time.
+	next(start time.Time) time.Time
+}
+
+// fixedBuckets are buckets of equal duration, aligned to multiples of the
+// duration since the zero time.
+type fixedBuckets time.Duration
+
+func (c fixedBuckets) start(t time.Time) time.Time {
+	return t.Truncate(time.Duration(c))
+}
+
+func (c fixedBuckets) next(start time.Time) time.Time {
+	return start.Add(time.Duration(c))
+}
+
+// calendarBuckets are calendar periods in a location. Their duration varies
+// with the length of each month and daylight saving transitions.
+type calendarBuckets struct {
+	calendar Calendar
+	location *time.Location
+}
+
+func newCalendarBuckets(calendar Calendar, location *time.Location) (*calendarBuckets, error) {
+	if _, ok := calendarNames[calendar]; !ok {
+		return nil, ErrUnknownCalendar
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	return &calendarBuckets{calendar, location}, nil
+}
+
+func (c *calendarBuckets) start(t time.Time) time.Time {
+	t = t.In(c.location)
+	year, month, day := t.Date()
+	switch c.calendar {
+	case CalendarWeek:
+		day -= (int(t.Weekday()) + 6) % 7
+	case CalendarMonth:
+		day = 1
+	case CalendarQuarter:
+		day = 1
+		month -= (month - 1) % 3
+	}
+
+	return time.Date(year, month, day, 0, 0, 0, 0, c.location)
+}
+
+func (c *calendarBuckets) next(start time.Time) time.Time {
+	year, month, day := start.In(c.location).Date()
+	switch c.calendar {
+	case CalendarDay:
+		day++
+	case CalendarWeek:
+		day += 7
+	case CalendarMonth:
+		month++
+	case CalendarQuarter:
+		month += 3
+	}
+
+	return time.Date(year, month, day, 0, 0, 0, 0, c.location)
+}
+
+// AggregateCalendar returns one data point for each calendar period between
+// minTime and maxTime, computed from the numeric values which fall within the
+// period. Periods begin at local midnight in the given location, or UTC if
+// location is nil, and the timestamp of each data point is the start of its
+// period in that location.
+//
+// Periods are computed from page summaries, so only pages which span the
+// boundary of a period are decoded.
+func (c *NonperiodicCollection) AggregateCalendar(seriesId interface{}, minTime time.Time, maxTime time.Time, calendar Calendar, location *time.Location, aggregator Aggregator) (DataPoints, error) {
+	b, err := newCalendarBuckets(calendar, location)
+	if err != nil {
+		return nil, wrapError("AggregateCalendar", seriesId, err)
+	}
+
+	points, err := c.aggregatePages(seriesId, minTime, maxTime, b, aggregator)
+	if err != nil {
+		return nil, wrapError("AggregateCalendar", seriesId, err)
+	}
+
+	return points, nil
+}
+
+// ResampleCalendar returns one data point for every calendar period between
+// minTime and maxTime, with periods beginning at local midnight in the given
+// location, or UTC if location is nil. Values are computed and filled as per
+// Resample.
+func (c *NonperiodicCollection) ResampleCalendar(seriesId interface{}, minTime time.Time, maxTime time.Time, calendar Calendar, location *time.Location, fill FillPolicy) (DataPoints, error) {
+	b, err := newCalendarBuckets(calendar, location)
+	if err != nil {
+		return nil, wrapError("ResampleCalendar", seriesId, err)
+	}
+
+	if _, ok := fillPolicyNames[fill]; !ok {
+		return nil, wrapError("ResampleCalendar", seriesId, ErrUnknownFillPolicy)
+	}
+
+	// walk all pages which overlap the range
+	pages, err := c.findPages(seriesId, minTime, maxTime, bson.M{"padding": 0})
+	if err != nil {
+		return nil, wrapError("ResampleCalendar", seriesId, err)
+	}
+
+	timestamps, values, err := pagesFloat64(pages)
+	if err != nil {
+		return nil, wrapError("ResampleCalendar", seriesId, err)
+	}
+
+	return resample(timestamps, values, minTime, maxTime, b, fill), nil
+}
@@ -0,0 +1,132 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestCalendarBuckets(t *testing.T) {
+	location, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %s", err.Error())
+	}
+
+	tests := []struct {
+		calendar Calendar
+		t        string
+		start    string
+		next     string
+	}{
+		{CalendarDay, "2021-03-28T12:00:00+01:00", "2021-03-28T00:00:00Z", "2021-03-29T00:00:00+01:00"},
+		{CalendarDay, "2021-10-31T00:30:00+01:00", "2021-10-31T00:00:00+01:00", "2021-11-01T00:00:00Z"},
+		{CalendarWeek, "2021-03-28T23:59:00+01:00", "2021-03-22T00:00:00Z", "2021-03-29T00:00:00+01:00"},
+		{CalendarWeek, "2021-03-29T00:00:00+01:00", "2021-03-29T00:00:00+01:00", "2021-04-05T00:00:00+01:00"},
+		{CalendarMonth, "2021-01-31T23:00:00Z", "2021-01-01T00:00:00Z", "2021-02-01T00:00:00Z"},
+		{CalendarMonth, "2021-06-30T23:30:00Z", "2021-07-01T00:00:00+01:00", "2021-08-01T00:00:00+01:00"},
+		{CalendarQuarter, "2021-12-31T23:59:59Z", "2021-10-01T00:00:00+01:00", "2022-01-01T00:00:00Z"},
+		{CalendarQuarter, "2021-03-31T23:30:00Z", "2021-04-01T00:00:00+01:00", "2021-07-01T00:00:00+01:00"},
+	}
+
+	parse := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		return v
+	}
+
+	for _, test := range tests {
+		b, err := newCalendarBuckets(test.calendar, location)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		start := b.start(parse(test.t))
+		if !start.Equal(parse(test.start)) {
+			t.Errorf("Expected %s bucket of %s to start at %s, got %s", test.calendar, test.t, test.start, start.Format(time.RFC3339))
+		}
+
+		next := b.next(start)
+		if !next.Equal(parse(test.next)) {
+			t.Errorf("Expected %s bucket after %s to start at %s, got %s", test.calendar, test.start, test.next, next.Format(time.RFC3339))
+		}
+	}
+}
+
+func TestAggregateCalendar(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %s", err.Error())
+	}
+
+	database := DBConnect()
+	name := "test_aggregate_calendar"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add a value of 1 every hour across the start of daylight saving time
+	seriesId := bson.NewObjectId()
+	startTime := time.Date(2021, 3, 12, 0, 0, 0, 0, location)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	endTime := time.Date(2021, 3, 17, 0, 0, 0, 0, location)
+	for ts := startTime; ts.Before(endTime); ts = ts.Add(time.Hour) {
+		err = collection.Append(seriesId, ts, 1)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// Each local day has 24 hours, except the 14th of March which has 23
+	points, err := collection.AggregateCalendar(seriesId, startTime, endTime.Add(-time.Nanosecond), CalendarDay, location, AggregateSum)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(points) != 5 {
+		t.Fatalf("Expected 5 days, got %d", len(points))
+	}
+
+	for i, point := range points {
+		day := time.Date(2021, 3, 12+i, 0, 0, 0, 0, location)
+		expected := 24.0
+		if day.Day() == 14 {
+			expected = 23
+		}
+
+		v, err := point.Float64()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if !point.Timestamp().Equal(day) || v != expected {
+			t.Errorf("Expected %v at %s, got %v at %s", expected, day.Format(layout), v, point.Timestamp().Format(layout))
+		}
+	}
+
+	// Resampling by week returns the week starting Monday the 8th and the
+	// week starting Monday the 15th
+	points, err = collection.ResampleCalendar(seriesId, startTime, endTime.Add(-time.Nanosecond), CalendarWeek, location, FillNull)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 weeks, got %d", len(points))
+	}
+
+	for i, day := range []int{8, 15} {
+		expected := time.Date(2021, 3, day, 0, 0, 0, 0, location)
+		if !points[i].Timestamp().Equal(expected) {
+			t.Errorf("Expected week starting %s, got %s", expected.Format(layout), points[i].Timestamp().Format(layout))
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Command mgots-query executes a query written in the mgots query language
+// against a nonperiodic collection and prints the resulting series as tab
+// separated values.
+//
+// Usage:
+//
+//	mgots-query [-url mongodb://localhost/mgots] -collection metrics 'avg by (host) ({metric="cpu"}) from -1d step 1h'
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cavaliercoder/mgots"
+	"github.com/cavaliercoder/mgots/expr"
+	"github.com/cavaliercoder/mgots/query"
+	"gopkg.in/mgo.v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	url := flag.String("url", "mongodb://localhost/mgots", "MongoDB connection URL, including the database name")
+	name := flag.String("collection", "", "name of the time series collection")
+	pageSize := flag.Int("pagesize", 4096, "page size of the time series collection")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] query\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *name == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	session, err := mgo.Dial(*url)
+	if err != nil {
+		fatal(err)
+	}
+	defer session.Close()
+
+	collection, err := mgots.NewNonperiodicCollection(session.DB(""), *name, *pageSize)
+	if err != nil {
+		fatal(err)
+	}
+
+	result, err := query.Run(collection, strings.Join(flag.Args(), " "), time.Now())
+	if err != nil {
+		fatal(err)
+	}
+
+	for _, series := range result.Series {
+		name := expr.Select(series.Tags).String()
+		for i, v := range series.Values {
+			fmt.Printf("%s\t%s\t%s\n", name, result.Timestamps[i].Format(time.RFC3339), strconv.FormatFloat(v, 'g', -1, 64))
+		}
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -15,8 +15,35 @@ type collection struct {
 
 type Collection interface {
 	CreateSeries(seriesId interface{}, startTime time.Time) error
+	CreateFloat64Series(seriesId interface{}, startTime time.Time) error
 	Append(seriesId interface{}, timestamp time.Time, value interface{}) error
+	AppendWithOptions(seriesId interface{}, timestamp time.Time, value interface{}, options AppendOptions) error
+	AppendMany(seriesId interface{}, entries []Entry, options AppendOptions) error
 	Update(seriedId interface{}, value interface{}) error
 	Range(seriesId interface{}, minTime time.Time, maxTime time.Time) (DataPoints, error)
+	RangeWithOptions(seriesId interface{}, minTime time.Time, maxTime time.Time, options RangeOptions) (DataPoints, string, error)
+	RangeWhere(seriesId interface{}, minTime time.Time, maxTime time.Time, filter Filter) (DataPoints, error)
+	RangeMaxPoints(seriesId interface{}, minTime time.Time, maxTime time.Time, n int) (DataPoints, error)
+	RangeFloat64(seriesId interface{}, minTime time.Time, maxTime time.Time) ([]time.Time, []float64, error)
+	Count(seriesId interface{}, minTime time.Time, maxTime time.Time) (int, error)
+	HasData(seriesId interface{}, minTime time.Time, maxTime time.Time) (bool, error)
 	Latest(seriesId interface{}) (DataPoint, error)
+	First(seriesId interface{}) (DataPoint, error)
+	Before(seriesId interface{}, t time.Time) (DataPoint, error)
+	After(seriesId interface{}, t time.Time) (DataPoint, error)
+	Nearest(seriesId interface{}, t time.Time) (DataPoint, error)
+	Info(seriesId interface{}) (*SeriesInfo, error)
+	Stats(seriesId interface{}) (*SeriesStats, error)
+	Aggregate(seriesId interface{}, minTime time.Time, maxTime time.Time, interval time.Duration, aggregator Aggregator) (DataPoints, error)
+	AggregateCalendar(seriesId interface{}, minTime time.Time, maxTime time.Time, calendar Calendar, location *time.Location, aggregator Aggregator) (DataPoints, error)
+	Resample(seriesId interface{}, minTime time.Time, maxTime time.Time, step time.Duration, fill FillPolicy) (DataPoints, error)
+	ResampleCalendar(seriesId interface{}, minTime time.Time, maxTime time.Time, calendar Calendar, location *time.Location, fill FillPolicy) (DataPoints, error)
+	Quantile(seriesId interface{}, minTime time.Time, maxTime time.Time, interval time.Duration, q float64) (DataPoints, error)
+	SketchQuantile(seriesId interface{}, minTime time.Time, maxTime time.Time, interval time.Duration, q float64) (DataPoints, error)
+	RangeRate(seriesId interface{}, minTime time.Time, maxTime time.Time, step time.Duration, fn CounterFunc) (DataPoints, error)
+	IntervalsWhere(seriesId interface{}, minTime time.Time, maxTime time.Time, predicate Predicate) (*IntervalSet, error)
+	StateTimeline(seriesId interface{}, minTime time.Time, maxTime time.Time) (*StateTimeline, error)
+	ListSeries() ([]interface{}, error)
+	SetTags(seriesId interface{}, tags map[string]string) error
+	FindSeries(tags map[string]string) ([]interface{}, error)
 }
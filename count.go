@@ -0,0 +1,76 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+// Count returns the number of data points between minTime and maxTime. Pages
+// which fall entirely within the range are counted from their summaries, and
+// only the timestamps of all other pages are fetched.
+func (c *NonperiodicCollection) Count(seriesId interface{}, minTime time.Time, maxTime time.Time) (int, error) {
+	// fetch headers of all pages in the range
+	pages, err := c.findPages(seriesId, minTime, maxTime, bson.M{"timestamps": 0, "values": 0, "float64s": 0, "padding": 0, "sketch": 0})
+	if err != nil {
+		return 0, wrapError("Count", seriesId, err)
+	}
+
+	count := 0
+	decode := make([]interface{}, 0)
+	for _, page := range pages {
+		if page.summarized() && !page.StartTime.Before(minTime) && !page.EndTime.After(maxTime) {
+			count += page.Summary.Count
+		} else {
+			decode = append(decode, page.PageId)
+		}
+	}
+
+	if len(decode) == 0 {
+		return count, nil
+	}
+
+	// count the timestamps of all other pages
+	var page dataPage
+	iter := c.DBCollection.Find(bson.M{"_id": bson.M{"$in": decode}}).Select(bson.M{"timestamps": 1}).Iter()
+	for iter.Next(&page) {
+		for _, timestamp := range page.Timestamps {
+			if !timestamp.IsZero() && !timestamp.Before(minTime) && !timestamp.After(maxTime) {
+				count++
+			}
+		}
+		page = dataPage{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return 0, wrapError("Count", seriesId, newError(err, "Error fetching page timestamps"))
+	}
+
+	return count, nil
+}
+
+// HasData returns true if any data points exist between minTime and maxTime.
+// Only the timestamps of pages in the range are fetched.
+func (c *NonperiodicCollection) HasData(seriesId interface{}, minTime time.Time, maxTime time.Time) (bool, error) {
+	iter := c.DBCollection.Find(bson.M{
+		"seriesid":  seriesId,
+		"starttime": bson.M{"$lte": maxTime},
+		"endtime":   bson.M{"$gte": minTime},
+	}).Select(bson.M{"timestamps": 1}).Sort("starttime").Iter()
+
+	var page dataPage
+	for iter.Next(&page) {
+		for _, timestamp := range page.Timestamps {
+			if !timestamp.IsZero() && !timestamp.Before(minTime) && !timestamp.After(maxTime) {
+				iter.Close()
+				return true, nil
+			}
+		}
+		page = dataPage{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return false, wrapError("HasData", seriesId, newError(err, "Error fetching page timestamps"))
+	}
+
+	return false, nil
+}
@@ -0,0 +1,92 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestCount(t *testing.T) {
+	database := DBConnect()
+	name := "test_count"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add a numeric value every minute, followed by string values which
+	// are not summarized
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	entryCount := 1000
+	for i := 0; i < entryCount; i++ {
+		var value interface{} = float64(i)
+		if i >= entryCount/2 {
+			value = "value"
+		}
+
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Minute), value)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	for _, r := range [][2]int{{0, 999}, {-10, 2000}, {13, 487}, {250, 750}, {600, 601}, {42, 42}} {
+		minTime := startTime.Add(time.Duration(r[0]) * time.Minute)
+		maxTime := startTime.Add(time.Duration(r[1]) * time.Minute)
+
+		expected := r[1] - r[0] + 1
+		if r[0] < 0 {
+			expected = entryCount
+		}
+
+		count, err := collection.Count(seriesId, minTime, maxTime)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if count != expected {
+			t.Errorf("Expected %d data points between %d and %d minutes, got %d", expected, r[0], r[1], count)
+		}
+
+		hasData, err := collection.HasData(seriesId, minTime, maxTime)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if !hasData {
+			t.Errorf("Expected data between %d and %d minutes", r[0], r[1])
+		}
+	}
+
+	// ranges between and outside of entries
+	for _, r := range [][2]time.Duration{{10*time.Minute + time.Second, 11*time.Minute - time.Second}, {-time.Hour, -time.Second}, {1000 * time.Minute, 2000 * time.Minute}} {
+		minTime := startTime.Add(r[0])
+		maxTime := startTime.Add(r[1])
+
+		count, err := collection.Count(seriesId, minTime, maxTime)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if count != 0 {
+			t.Errorf("Expected no data points between %v and %v, got %d", r[0], r[1], count)
+		}
+
+		hasData, err := collection.HasData(seriesId, minTime, maxTime)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if hasData {
+			t.Errorf("Expected no data between %v and %v", r[0], r[1])
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"time"
+)
+
+// CounterFunc computes one value for each step of a range of numeric data
+// points, such as the per-second rate of a counter. Rate, IRate, Increase and
+// Derivative are CounterFuncs.
+type CounterFunc func(points DataPoints, step time.Duration) (DataPoints, error)
+
+// Rate returns the average per-second rate of increase of a counter for each
+// step. Decreasing values are treated as counter resets. Steps in which all
+// data points share a timestamp have a rate of NaN.
+func Rate(points DataPoints, step time.Duration) (DataPoints, error) {
+	return applyWindows(points, step, func(window []sample) float64 {
+		elapsed := window[len(window)-1].timestamp.Sub(window[0].timestamp).Seconds()
+		if elapsed == 0 {
+			return math.NaN()
+		}
+		return increase(window) / elapsed
+	})
+}
+
+// IRate returns the instantaneous per-second rate of increase of a counter for
+// each step, computed from the last two data points in the step. A decreasing
+// value is treated as a counter reset. If the last two data points share a
+// timestamp, the rate is NaN.
+func IRate(points DataPoints, step time.Duration) (DataPoints, error) {
+	return applyWindows(points, step, func(window []sample) float64 {
+		window = window[len(window)-2:]
+		elapsed := window[1].timestamp.Sub(window[0].timestamp).Seconds()
+		if elapsed == 0 {
+			return math.NaN()
+		}
+		return increase(window) / elapsed
+	})
+}
+
+// Increase returns the total increase of a counter for each step. Decreasing
+// values are treated as counter resets.
+func Increase(points DataPoints, step time.Duration) (DataPoints, error) {
+	return applyWindows(points, step, increase)
+}
+
+// Derivative returns the per-second derivative of a gauge for each step,
+// computed as the least squares slope of the data points in the step. Unlike
+// Rate, decreasing values are not treated as counter resets. Steps in which all
+// data points share a timestamp have a derivative of NaN.
+func Derivative(points DataPoints, step time.Duration) (DataPoints, error) {
+	return applyWindows(points, step, func(window []sample) float64 {
+		origin := window[0].timestamp
+		var n, sumX, sumY, sumXY, sumXX float64
+		for _, s := range window {
+			x := s.timestamp.Sub(origin).Seconds()
+			n++
+			sumX += x
+			sumY += s.value
+			sumXY += x * s.value
+			sumXX += x * x
+		}
+
+		d := n*sumXX - sumX*sumX
+		if d == 0 {
+			return math.NaN()
+		}
+		return (n*sumXY - sumX*sumY) / d
+	})
+}
+
+// RangeRate applies a CounterFunc to the data points of a series between
+// minTime and maxTime. Unlike applying the CounterFunc to the result of
+// Range, the most recent data point before minTime is included, even if it
+// is stored in a previous page, so the first step is computed from the same
+// number of samples as every other step.
+func (c *NonperiodicCollection) RangeRate(seriesId interface{}, minTime time.Time, maxTime time.Time, step time.Duration, fn CounterFunc) (DataPoints, error) {
+	// pages are matched by their end time, which is the timestamp of the
+	// first entry in the next page, so the page containing the entry
+	// preceding minTime is always included
+	pages, err := c.findPages(seriesId, minTime, maxTime, bson.M{"padding": 0})
+	if err != nil {
+		return nil, wrapError("RangeRate", seriesId, err)
+	}
+
+	timestamps, values, err := pagesFloat64(pages)
+	if err != nil {
+		return nil, wrapError("RangeRate", seriesId, err)
+	}
+
+	points := make(DataPoints, 0, len(timestamps))
+	for i, timestamp := range timestamps {
+		if timestamp.After(maxTime) {
+			break
+		}
+
+		if timestamp.Before(minTime) {
+			if i+1 < len(timestamps) && timestamps[i+1].Before(minTime) {
+				continue
+			}
+		}
+
+		points = append(points, newFloat64DataPoint(timestamp, values[i]))
+	}
+
+	results, err := fn(points, step)
+	if err != nil {
+		return nil, wrapError("RangeRate", seriesId, err)
+	}
+
+	// discard steps which precede the range
+	start := minTime.Truncate(step)
+	for len(results) > 0 && results[0].Timestamp().Before(start) {
+		results = results[1:]
+	}
+
+	return results, nil
+}
+
+// applyWindows groups chronologically ordered data points into steps aligned
+// to multiples of the step since the zero time, and computes a value for each
+// step from its window. The window of each step includes the last data point
+// of the preceding steps, so no change between steps is lost. Null data
+// points are skipped and steps with a window of less than two data points are
+// omitted.
+func applyWindows(points DataPoints, step time.Duration, fn func(window []sample) float64) (DataPoints, error) {
+	if step <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	results := make(DataPoints, 0)
+	window := make([]sample, 0)
+	var start time.Time
+	flush := func() {
+		if len(window) > 1 {
+			results = append(results, newFloat64DataPoint(start, fn(window)))
+		}
+
+		// carry the last data point into the next window
+		if len(window) > 0 {
+			window = window[len(window)-1:]
+		}
+	}
+
+	for _, point := range points {
+		v, err := point.Float64()
+		if err != nil {
+			return nil, err
+		}
+
+		if math.IsNaN(v) {
+			continue
+		}
+
+		timestamp := point.Timestamp()
+		if len(window) == 0 {
+			start = timestamp.Truncate(step)
+		} else if s := timestamp.Truncate(step); !s.Equal(start) {
+			flush()
+			start = s
+		}
+
+		window = append(window, sample{timestamp, v})
+	}
+	flush()
+
+	return results, nil
+}
+
+// increase returns the total increase of a counter over the given samples,
+// treating decreasing values as counter resets.
+func increase(window []sample) float64 {
+	total := 0.0
+	for i := 1; i < len(window); i++ {
+		if window[i].value < window[i-1].value {
+			// counter reset from zero
+			total += window[i].value
+		} else {
+			total += window[i].value - window[i-1].value
+		}
+	}
+
+	return total
+}
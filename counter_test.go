@@ -0,0 +1,145 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"testing"
+	"time"
+)
+
+type counterTest struct {
+	Name     string
+	Func     CounterFunc
+	Expected []float64
+}
+
+func TestCounters(t *testing.T) {
+	database := DBConnect()
+	name := "test_counters"
+
+	// Create a nonperiodic collection with small pages
+	collection, err := NewNonperiodicCollection(database, name, 256)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Create a counter which increments by 15 every 15 seconds and resets
+	// after one minute
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i, v := range []float64{0, 15, 30, 45, 5, 20, 35, 50} {
+		timestamp := startTime.Add(time.Duration(i*15) * time.Second)
+		err = collection.Append(seriesId, timestamp, v)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	points, err := collection.Range(seriesId, startTime, startTime.Add(time.Hour))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	tests := []counterTest{
+		{"Rate", Rate, []float64{1, 50.0 / 60}},
+		{"IRate", IRate, []float64{1, 1}},
+		{"Increase", Increase, []float64{45, 50}},
+		{"Derivative", Derivative, []float64{1, 600.0 / 2250}},
+	}
+
+	for _, test := range tests {
+		data, err := test.Func(points, time.Minute)
+		if err != nil {
+			t.Fatalf("Error computing %s: %s", test.Name, err.Error())
+		}
+
+		if len(data) != len(test.Expected) {
+			t.Fatalf("Expected %d steps from %s, got %d", len(test.Expected), test.Name, len(data))
+		}
+
+		for i, point := range data {
+			v, _ := point.Float64()
+			if math.Abs(v-test.Expected[i]) > 1e-9 {
+				t.Errorf("Expected %s step %d to be %v, got %v", test.Name, i, test.Expected[i], v)
+			}
+		}
+	}
+
+	// The second step should include the increase from the data point
+	// preceding the range
+	data, err := collection.RangeRate(seriesId, startTime.Add(time.Minute), startTime.Add(time.Hour), time.Minute, Increase)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(data) != 1 {
+		t.Fatalf("Expected 1 step from RangeRate, got %d", len(data))
+	}
+
+	if v, _ := data[0].Float64(); v != 50 {
+		t.Errorf("Expected RangeRate to return an increase of 50, got %v", v)
+	}
+}
+
+func TestCounterDuplicates(t *testing.T) {
+	// every data point in the step shares a timestamp
+	timestamp := time.Now().Truncate(time.Hour)
+	points := DataPoints{
+		NewFloat64DataPoint(timestamp, 1),
+		NewFloat64DataPoint(timestamp, 2),
+	}
+
+	for name, fn := range map[string]CounterFunc{"Rate": Rate, "IRate": IRate, "Derivative": Derivative} {
+		results, err := fn(points, time.Minute)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 %s step, got %d", name, len(results))
+		}
+
+		if v, _ := results[0].Float64(); !math.IsNaN(v) {
+			t.Errorf("Expected a %s of NaN, got %v", name, v)
+		}
+	}
+}
+
+func TestCounterNull(t *testing.T) {
+	// a null entry between counter samples is skipped
+	timestamp := time.Now().Truncate(time.Hour)
+	points := DataPoints{
+		NewFloat64DataPoint(timestamp, 10),
+		newNullDataPoint(timestamp.Add(10 * time.Second)),
+		NewFloat64DataPoint(timestamp.Add(20*time.Second), 30),
+	}
+
+	tests := map[string]struct {
+		fn       CounterFunc
+		expected float64
+	}{
+		"Rate":     {Rate, 1},
+		"IRate":    {IRate, 1},
+		"Increase": {Increase, 20},
+	}
+
+	for name, test := range tests {
+		results, err := test.fn(points, time.Minute)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 %s step, got %d", name, len(results))
+		}
+
+		if v, _ := results[0].Float64(); v != test.expected {
+			t.Errorf("Expected a %s of %v, got %v", name, test.expected, v)
+		}
+	}
+}
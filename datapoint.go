@@ -1,7 +1,9 @@
 package mgots
 
 import (
+	"errors"
 	"gopkg.in/mgo.v2/bson"
+	"math"
 	"time"
 )
 
@@ -13,10 +15,45 @@ type dataPoint struct {
 type DataPoint interface {
 	Timestamp() time.Time
 	GetValue(v interface{}) error
+	Float64() (float64, error)
 }
 
 type DataPoints []DataPoint
 
+// Errors
+var ErrNotNumeric = errors.New("The value of the data point is not numeric")
+
+// NewFloat64DataPoint returns a data point for a computed numeric value.
+func NewFloat64DataPoint(timestamp time.Time, value float64) DataPoint {
+	return newFloat64DataPoint(timestamp, value)
+}
+
+// newFloat64DataPoint returns a data point for a computed numeric value.
+func newFloat64DataPoint(timestamp time.Time, value float64) *dataPoint {
+	b, err := bson.Marshal(bson.M{"v": value})
+	if err != nil {
+		panic(err)
+	}
+
+	var doc struct{ V bson.Raw }
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		panic(err)
+	}
+
+	return &dataPoint{
+		timestamp: timestamp,
+		value:     doc.V,
+	}
+}
+
+// newNullDataPoint returns a data point with a null value.
+func newNullDataPoint(timestamp time.Time) *dataPoint {
+	return &dataPoint{
+		timestamp: timestamp,
+		value:     bsonZero,
+	}
+}
+
 func (c *dataPoint) Timestamp() time.Time {
 	return c.timestamp
 }
@@ -24,3 +61,59 @@ func (c *dataPoint) Timestamp() time.Time {
 func (c *dataPoint) GetValue(v interface{}) error {
 	return c.value.Unmarshal(v)
 }
+
+// Float64 returns the value of the data point as a float64 if it was stored
+// as a BSON double, 32-bit integer or 64-bit integer. Null values are returned
+// as NaN.
+func (c *dataPoint) Float64() (float64, error) {
+	return rawFloat64(c.value)
+}
+
+func rawFloat64(raw bson.Raw) (float64, error) {
+	var v interface{}
+	if err := raw.Unmarshal(&v); err != nil {
+		return 0, err
+	}
+
+	if v == nil {
+		return math.NaN(), nil
+	}
+
+	if f, ok := toFloat64(v); ok {
+		return f, nil
+	}
+
+	return 0, ErrNotNumeric
+}
+
+// toFloat64 converts any Go numeric value to a float64.
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+
+	return 0, false
+}
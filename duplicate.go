@@ -0,0 +1,54 @@
+package mgots
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DuplicatePolicy determines how Append handles an entry with the same
+// timestamp as the most recent entry of a series. Timestamps are compared at
+// the millisecond precision with which they are stored.
+type DuplicatePolicy int
+
+const (
+	DuplicateReject    DuplicatePolicy = iota // Duplicates are rejected with ErrDuplicateTimestamp
+	DuplicateOverwrite                        // Duplicates replace the value of the most recent entry, as per Update
+	DuplicateKeep                             // Duplicates are appended as an additional entry
+	DuplicateIgnore                           // Duplicates are discarded without error
+)
+
+var duplicatePolicyNames = map[DuplicatePolicy]string{
+	DuplicateReject:    "reject",
+	DuplicateOverwrite: "overwrite",
+	DuplicateKeep:      "keep",
+	DuplicateIgnore:    "ignore",
+}
+
+// Errors
+var ErrUnknownDuplicatePolicy = errors.New("Unknown duplicate timestamp policy")
+
+// ErrDuplicateTimestamp wraps ErrTooOld, so duplicates are also matched by
+// errors.Is(err, ErrTooOld).
+var ErrDuplicateTimestamp = fmt.Errorf("The timestamp of the specified value is the same as the most recent entry: %w", ErrTooOld)
+
+// ParseDuplicatePolicy returns the DuplicatePolicy with the given name (E.g.
+// "overwrite").
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	name = strings.ToLower(name)
+	for p, n := range duplicatePolicyNames {
+		if n == name {
+			return p, nil
+		}
+	}
+
+	return 0, ErrUnknownDuplicatePolicy
+}
+
+func (c DuplicatePolicy) String() string {
+	if name, ok := duplicatePolicyNames[c]; ok {
+		return name
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,88 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestDuplicatePolicy(t *testing.T) {
+	database := DBConnect()
+	name := "test_duplicate_policy"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	tests := []struct {
+		policy   DuplicatePolicy
+		expected []float64 // values after appending 1, 1.5, a duplicate 2 and 3
+	}{
+		{DuplicateReject, []float64{1, 1.5, 3}},
+		{DuplicateOverwrite, []float64{1, 2, 3}},
+		{DuplicateKeep, []float64{1, 1.5, 2, 3}},
+		{DuplicateIgnore, []float64{1, 1.5, 3}},
+	}
+
+	for _, test := range tests {
+		collection.Duplicates = test.policy
+
+		seriesId := bson.NewObjectId()
+		err = collection.CreateSeries(seriesId, startTime)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		err = collection.Append(seriesId, startTime, 1.0)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		err = collection.Append(seriesId, startTime.Add(time.Minute), 1.5)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		// the duplicate differs from the original below millisecond precision
+		err = collection.Append(seriesId, startTime.Add(time.Minute+time.Microsecond), 2.0)
+		if test.policy == DuplicateReject {
+			if !errors.Is(err, ErrDuplicateTimestamp) || !errors.Is(err, ErrTooOld) {
+				t.Errorf("Expected ErrDuplicateTimestamp for the %v policy, got %v", test.policy, err)
+			}
+		} else if err != nil {
+			t.Fatalf("Error appending a duplicate with the %v policy: %v", test.policy, err)
+		}
+
+		// older entries are still too old
+		err = collection.Append(seriesId, startTime.Add(time.Second), 0.0)
+		if !errors.Is(err, ErrTooOld) || errors.Is(err, ErrDuplicateTimestamp) {
+			t.Errorf("Expected ErrTooOld for the %v policy, got %v", test.policy, err)
+		}
+
+		err = collection.Append(seriesId, startTime.Add(2*time.Minute), 3.0)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		_, values, err := collection.RangeFloat64(seriesId, startTime, startTime.Add(time.Hour))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if len(values) != len(test.expected) {
+			t.Errorf("Expected %v for the %v policy, got %v", test.expected, test.policy, values)
+			continue
+		}
+
+		for i, v := range values {
+			if v != test.expected[i] {
+				t.Errorf("Expected %v for the %v policy, got %v", test.expected, test.policy, values)
+				break
+			}
+		}
+	}
+}
@@ -1,25 +1,207 @@
 package mgots
 
 import (
+	"errors"
 	"fmt"
+	"gopkg.in/mgo.v2"
+	"io"
+	"net"
+	"strings"
 )
 
-type mgotsError struct {
-	InnerError error
+// ErrorKind classifies errors so callers can decide how to handle them
+// without matching every sentinel error. ErrorKind implements error so it may
+// be used as the target of errors.Is.
+//
+//	if errors.Is(err, mgots.ErrorKindTransient) {
+//		// retry
+//	}
+type ErrorKind int
+
+const (
+	ErrorKindOther     ErrorKind = iota // Unclassified errors
+	ErrorKindNotFound                   // The series or data does not exist
+	ErrorKindConflict                   // The series or data already exists
+	ErrorKindTooOld                     // A timestamp precedes the most recent entry
+	ErrorKindInvalid                    // An argument or value is invalid
+	ErrorKindTransient                  // A network or server failure which may succeed if retried
+)
+
+var errorKindNames = map[ErrorKind]string{
+	ErrorKindOther:     "other error",
+	ErrorKindNotFound:  "not found",
+	ErrorKindConflict:  "conflict",
+	ErrorKindTooOld:    "too old",
+	ErrorKindInvalid:   "invalid",
+	ErrorKindTransient: "transient error",
+}
+
+func (c ErrorKind) Error() string {
+	return errorKindNames[c]
+}
+
+// transientCodes are MongoDB server error codes which indicate that an
+// operation may succeed if retried.
+var transientCodes = map[int]bool{
+	6:     true, // HostUnreachable
+	7:     true, // HostNotFound
+	89:    true, // NetworkTimeout
+	91:    true, // ShutdownInProgress
+	189:   true, // PrimarySteppedDown
+	262:   true, // ExceededTimeLimit
+	9001:  true, // SocketException
+	10107: true, // NotMaster
+	11600: true, // InterruptedAtShutdown
+	11602: true, // InterruptedDueToReplStateChange
+	13435: true, // NotMasterNoSlaveOk
+	13436: true, // NotMasterOrSecondary
+}
+
+// Error is the type of all errors returned by a Collection. It describes the
+// operation and series which caused the error and wraps any underlying error,
+// so both sentinel errors (E.g. ErrTooOld) and error kinds (E.g.
+// ErrorKindTransient) can be matched with errors.Is.
+type Error struct {
+	Op         string      // Name of the Collection method which failed
+	SeriesId   interface{} // ID of the series being operated on, if any
+	Kind       ErrorKind
 	Message    string
+	InnerError error
 }
 
 func newError(err error, format string, a ...interface{}) error {
-	return &mgotsError{
-		InnerError: err,
+	return &Error{
+		Kind:       errorKind(err),
 		Message:    fmt.Sprintf(format, a...),
+		InnerError: err,
+	}
+}
+
+// wrapError annotates an error with the operation and series that caused it.
+// Errors which were already annotated by another operation are returned
+// unchanged. Errors are copied rather than annotated in place, as they may be
+// held by other callers.
+func wrapError(op string, seriesId interface{}, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if e, ok := err.(*Error); ok && e.Op == "" {
+		annotated := *e
+		annotated.Op = op
+		annotated.SeriesId = seriesId
+		return &annotated
+	}
+
+	var e *Error
+	if errors.As(err, &e) && e.Op != "" {
+		return err
+	}
+
+	return &Error{
+		Op:         op,
+		SeriesId:   seriesId,
+		Kind:       errorKind(err),
+		InnerError: err,
+	}
+}
+
+// invalidErrors are the sentinel errors of kind ErrorKindInvalid.
+var invalidErrors = []error{
+	ErrInvalidPageSize,
+	ErrValueTooLarge,
+	ErrNotNumeric,
+	ErrInvalidInterval,
+	ErrUnknownAggregator,
+	ErrUnknownFillPolicy,
+	ErrInvalidQuantile,
+	ErrInvalidMaxPoints,
+	ErrInvalidRollup,
+	ErrInvalidToken,
+	ErrUnknownCalendar,
+	ErrInvalidTag,
+	ErrUnknownComparison,
+	ErrUnknownDuplicatePolicy,
+}
+
+// isAny returns true if the given error matches any of the targets.
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// errorKind classifies the given error.
+func errorKind(err error) ErrorKind {
+	var e *Error
+	var netErr net.Error
+	var queryErr *mgo.QueryError
+	var lastErr *mgo.LastError
+
+	switch {
+	case err == nil:
+		return ErrorKindOther
+	case errors.As(err, &e):
+		return e.Kind
+	case errors.Is(err, ErrSeriesNotFound), errors.Is(err, ErrNoData), errors.Is(err, mgo.ErrNotFound):
+		return ErrorKindNotFound
+	case errors.Is(err, ErrDuplicateSeries), errors.Is(err, ErrWriteConflict), mgo.IsDup(err):
+		return ErrorKindConflict
+	case errors.Is(err, ErrTooOld):
+		return ErrorKindTooOld
+	case isAny(err, invalidErrors):
+		return ErrorKindInvalid
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &netErr):
+		return ErrorKindTransient
+	case errors.As(err, &queryErr) && transientCodes[queryErr.Code]:
+		return ErrorKindTransient
+	case errors.As(err, &lastErr) && transientCodes[lastErr.Code]:
+		return ErrorKindTransient
+	case strings.Contains(err.Error(), "no reachable servers"), strings.Contains(err.Error(), "Closed explicitly"):
+		return ErrorKindTransient
 	}
+
+	return ErrorKindOther
 }
 
-func (c *mgotsError) Error() string {
-	if c.InnerError == nil {
-		return c.Message
+func (c *Error) Error() string {
+	msg := c.Message
+	if c.InnerError != nil {
+		if msg == "" {
+			msg = c.InnerError.Error()
+		} else {
+			msg = fmt.Sprintf("%s: %s", msg, c.InnerError.Error())
+		}
+	}
+
+	if c.Op == "" {
+		return msg
 	}
 
-	return fmt.Sprintf("%s: %s", c.Message, c.InnerError.Error())
+	if c.SeriesId == nil {
+		return fmt.Sprintf("%s: %s", c.Op, msg)
+	}
+
+	return fmt.Sprintf("%s %v: %s", c.Op, c.SeriesId, msg)
+}
+
+// Unwrap returns the underlying error.
+func (c *Error) Unwrap() error {
+	return c.InnerError
+}
+
+// Is reports whether the error is of the given ErrorKind.
+func (c *Error) Is(target error) bool {
+	kind, ok := target.(ErrorKind)
+	return ok && kind == c.Kind
+}
+
+// IsTransient reports whether err was caused by a network or server failure
+// and the operation may succeed if retried.
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrorKindTransient)
 }
@@ -0,0 +1,103 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+type errorTest struct {
+	Err      error
+	Sentinel error
+	Kind     ErrorKind
+}
+
+func TestErrors(t *testing.T) {
+	database := DBConnect()
+	name := "test_errors"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	seriesId := bson.NewObjectId()
+	tests := make([]errorTest, 0)
+
+	// Missing series
+	_, err = collection.Latest(seriesId)
+	tests = append(tests, errorTest{err, ErrSeriesNotFound, ErrorKindNotFound})
+
+	// Duplicate series
+	collection.CreateSeries(seriesId, time.Now())
+	err = collection.CreateSeries(seriesId, time.Now())
+	tests = append(tests, errorTest{err, ErrDuplicateSeries, ErrorKindConflict})
+
+	// Old data
+	collection.Append(seriesId, time.Now(), 1)
+	err = collection.Append(seriesId, time.Now().AddDate(-1, 0, 0), 1)
+	tests = append(tests, errorTest{err, ErrTooOld, ErrorKindTooOld})
+
+	for i, test := range tests {
+		if !errors.Is(test.Err, test.Sentinel) {
+			t.Errorf("Expected error %d (%v) to wrap %v", i, test.Err, test.Sentinel)
+		}
+
+		if !errors.Is(test.Err, test.Kind) {
+			t.Errorf("Expected error %d (%v) to be of kind %v", i, test.Err, test.Kind)
+		}
+
+		var e *Error
+		if !errors.As(test.Err, &e) {
+			t.Errorf("Expected error %d (%v) to be an *Error", i, test.Err)
+		} else if e.SeriesId != seriesId || e.Op == "" {
+			t.Errorf("Expected error %d (%v) to describe the series and operation", i, test.Err)
+		}
+
+		if IsTransient(test.Err) {
+			t.Errorf("Expected error %d (%v) to not be transient", i, test.Err)
+		}
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	inner := newError(ErrNotNumeric, "Error decoding value")
+	err := wrapError("Range", "a", inner)
+
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "Range" || e.SeriesId != "a" {
+		t.Errorf("Expected the error to be annotated with the operation, got %v", err)
+	}
+
+	// the original error is not modified
+	if inner.(*Error).Op != "" {
+		t.Errorf("Expected the original error to be unchanged, got %v", inner)
+	}
+
+	// errors annotated by another operation are returned unchanged
+	if wrapError("Aggregate", "b", err) != err {
+		t.Errorf("Expected an annotated error to be returned unchanged")
+	}
+
+	if !errors.Is(err, ErrNotNumeric) || !errors.Is(err, ErrorKindInvalid) {
+		t.Errorf("Expected the error to wrap ErrNotNumeric, got %v", err)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []errorTest{
+		{ErrUnknownFillPolicy, ErrUnknownFillPolicy, ErrorKindInvalid},
+		{ErrInvalidQuantile, ErrInvalidQuantile, ErrorKindInvalid},
+		{ErrInvalidMaxPoints, ErrInvalidMaxPoints, ErrorKindInvalid},
+		{ErrStatsUnavailable, ErrStatsUnavailable, ErrorKindOther},
+	}
+
+	for _, test := range tests {
+		err := wrapError("Test", "a", test.Err)
+		if !errors.Is(err, test.Sentinel) || !errors.Is(err, test.Kind) {
+			t.Errorf("Expected %v to be of kind %v, got %v", test.Sentinel, test.Kind, errorKind(err))
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package expr evaluates expressions which derive a time series from other
+// mgots time series, such as the ratio of two series or the sum of all series
+// which share a tag.
+//
+// Every series referenced by an expression is resampled onto the steps of a
+// common range with Collection.Resample, so values can be combined step by
+// step. Steps without a value are NaN.
+package expr
+
+import (
+	"errors"
+	"github.com/cavaliercoder/mgots"
+	"math"
+	"time"
+)
+
+// Errors
+var ErrUnknownOperator = errors.New("Unknown operator")
+var ErrUnknownFunction = errors.New("Unknown function")
+var ErrArgumentCount = errors.New("Wrong number of function arguments")
+
+// Context describes the range over which an expression is evaluated.
+type Context struct {
+	Collection mgots.Collection
+	MinTime    time.Time
+	MaxTime    time.Time
+	Step       time.Duration
+
+	// Fill is the policy used to fill empty steps of each series. Defaults to
+	// FillNull.
+	Fill mgots.FillPolicy
+
+	timestamps []time.Time
+}
+
+// Timestamps returns the start of each step between MinTime and MaxTime,
+// aligned to multiples of the step since the zero time.
+func (c *Context) Timestamps() []time.Time {
+	if c.timestamps == nil {
+		c.timestamps = make([]time.Time, 0)
+		if c.Step > 0 {
+			for t := c.MinTime.Truncate(c.Step); !t.After(c.MaxTime); t = t.Add(c.Step) {
+				c.timestamps = append(c.timestamps, t)
+			}
+		}
+	}
+
+	return c.timestamps
+}
+
+// Series is a computed series with one value for each step of a Context.
+type Series struct {
+	Tags   map[string]string
+	Values []float64
+}
+
+// DataPoints returns the values of the series at the given timestamps, as
+// returned by Context.Timestamps.
+func (c *Series) DataPoints(timestamps []time.Time) mgots.DataPoints {
+	points := make(mgots.DataPoints, len(c.Values))
+	for i, v := range c.Values {
+		points[i] = mgots.NewFloat64DataPoint(timestamps[i], v)
+	}
+
+	return points
+}
+
+// Result is the result of evaluating an expression.
+type Result struct {
+	Timestamps []time.Time
+	Series     []Series
+}
+
+// Expr is an expression which computes a set of series.
+type Expr interface {
+	// Eval returns the series computed by the expression, each with one
+	// value for every step of the context.
+	Eval(ctx *Context) ([]Series, error)
+
+	String() string
+}
+
+// Evaluate returns the series computed by an expression over the range of the
+// given context.
+func Evaluate(ctx *Context, e Expr) (*Result, error) {
+	if ctx.Step <= 0 {
+		return nil, mgots.ErrInvalidInterval
+	}
+
+	series, err := e.Eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Timestamps: ctx.Timestamps(),
+		Series:     series,
+	}, nil
+}
+
+// nan returns a slice of n NaN values.
+func nan(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+
+	return values
+}
@@ -0,0 +1,186 @@
+package expr
+
+import (
+	"github.com/cavaliercoder/mgots"
+	"math"
+	"testing"
+	"time"
+)
+
+var startTime = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testCollection serves series of values at one minute steps from memory.
+type testCollection struct {
+	mgots.Collection
+	tags   map[string]map[string]string
+	values map[string][]float64
+}
+
+func (c *testCollection) Info(seriesId interface{}) (*mgots.SeriesInfo, error) {
+	tags, ok := c.tags[seriesId.(string)]
+	if !ok {
+		return nil, mgots.ErrSeriesNotFound
+	}
+
+	return &mgots.SeriesInfo{SeriesId: seriesId, Tags: tags}, nil
+}
+
+func (c *testCollection) FindSeries(tags map[string]string) ([]interface{}, error) {
+	ids := make([]interface{}, 0)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		match := true
+		for name, value := range tags {
+			if c.tags[id][name] != value {
+				match = false
+			}
+		}
+
+		if match {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (c *testCollection) Resample(seriesId interface{}, minTime time.Time, maxTime time.Time, step time.Duration, fill mgots.FillPolicy) (mgots.DataPoints, error) {
+	points := make(mgots.DataPoints, 0)
+	for i, v := range c.values[seriesId.(string)] {
+		timestamp := startTime.Add(time.Duration(i) * step)
+		if !timestamp.Before(minTime) && !timestamp.After(maxTime) {
+			points = append(points, mgots.NewFloat64DataPoint(timestamp, v))
+		}
+	}
+
+	return points, nil
+}
+
+func newTestCollection() *testCollection {
+	return &testCollection{
+		tags: map[string]map[string]string{
+			"a": {"metric": "errors", "host": "x"},
+			"b": {"metric": "errors", "host": "y"},
+			"c": {"metric": "requests", "host": "x"},
+			"d": {"metric": "requests", "host": "y"},
+		},
+		values: map[string][]float64{
+			"a": {1, 2, math.NaN(), 4},
+			"b": {0, 1, 1, 2},
+			"c": {10, 20, 30, 40},
+			"d": {10, 10, 10, 10},
+		},
+	}
+}
+
+type exprTest struct {
+	Expr     Expr
+	String   string
+	Expected map[string][]float64 // expected values by host tag
+}
+
+func TestEvaluate(t *testing.T) {
+	errs := Select(map[string]string{"metric": "errors"})
+	requests := Select(map[string]string{"metric": "requests"})
+
+	ratio := Binary(OpMul, Binary(OpDiv, errs, requests), Scalar(100))
+	ratio.LHS.(*BinaryExpr).On = []string{"host"}
+
+	tests := []exprTest{
+		{
+			Ref("a"),
+			`series("a")`,
+			map[string][]float64{"x": {1, 2, math.NaN(), 4}},
+		},
+		{
+			ratio,
+			`(({metric="errors"} / on (host) {metric="requests"}) * 100)`,
+			map[string][]float64{"x": {10, 10, math.NaN(), 10}, "y": {0, 10, 10, 20}},
+		},
+		{
+			Binary(OpDiv, Ref("a"), Ref("c")),
+			`(series("a") / series("c"))`,
+			map[string][]float64{"x": {0.1, 0.1, math.NaN(), 0.1}},
+		},
+		{
+			Aggregate(mgots.AggregateSum, errs),
+			`sum ({metric="errors"})`,
+			map[string][]float64{"": {1, 3, 1, 6}},
+		},
+		{
+			Aggregate(mgots.AggregateMax, Select(nil), "host"),
+			`max by (host) ({})`,
+			map[string][]float64{"x": {10, 20, 30, 40}, "y": {10, 10, 10, 10}},
+		},
+		{
+			Aggregate(mgots.AggregateCount, errs, "metric"),
+			`count by (metric) ({metric="errors"})`,
+			map[string][]float64{"": {2, 2, 1, 2}},
+		},
+		{
+			Func("clamp_max", requests, Scalar(15)),
+			`clamp_max({metric="requests"}, 15)`,
+			map[string][]float64{"x": {10, 15, 15, 15}, "y": {10, 10, 10, 10}},
+		},
+	}
+
+	ctx := &Context{
+		Collection: newTestCollection(),
+		MinTime:    startTime,
+		MaxTime:    startTime.Add(3 * time.Minute),
+		Step:       time.Minute,
+	}
+
+	for _, test := range tests {
+		if s := test.Expr.String(); s != test.String {
+			t.Errorf("Expected expression %s, got %s", test.String, s)
+		}
+
+		result, err := Evaluate(ctx, test.Expr)
+		if err != nil {
+			t.Fatalf("%s: %s", test.String, err.Error())
+		}
+
+		if len(result.Timestamps) != 4 {
+			t.Fatalf("%s: expected 4 steps, got %d", test.String, len(result.Timestamps))
+		}
+
+		if len(result.Series) != len(test.Expected) {
+			t.Fatalf("%s: expected %d series, got %d", test.String, len(test.Expected), len(result.Series))
+		}
+
+		for _, series := range result.Series {
+			expected, ok := test.Expected[series.Tags["host"]]
+			if !ok {
+				t.Fatalf("%s: unexpected series %v", test.String, series.Tags)
+			}
+
+			for i, v := range series.Values {
+				if !(v == expected[i] || math.IsNaN(v) && math.IsNaN(expected[i])) {
+					t.Errorf("%s: expected %v at step %d of %v, got %v", test.String, expected[i], i, series.Tags, v)
+				}
+			}
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	ctx := &Context{
+		Collection: newTestCollection(),
+		MinTime:    startTime,
+		MaxTime:    startTime.Add(3 * time.Minute),
+		Step:       time.Minute,
+	}
+
+	tests := map[Expr]error{
+		Func("nope", Scalar(1)):                    ErrUnknownFunction,
+		Func("abs", Scalar(1), Scalar(2)):          ErrArgumentCount,
+		Binary(Operator(99), Scalar(1), Scalar(2)): ErrUnknownOperator,
+		Aggregate(mgots.AggregateFirst, Ref("a")):  mgots.ErrUnknownAggregator,
+	}
+
+	for e, expected := range tests {
+		if _, err := Evaluate(ctx, e); err != expected {
+			t.Errorf("Expected %v for %s, got %v", expected, e, err)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package expr
+
+import (
+	"github.com/cavaliercoder/mgots"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Operator identifies an arithmetic operator of a BinaryExpr.
+type Operator int
+
+const (
+	OpAdd Operator = iota
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpPow
+)
+
+var operatorNames = map[Operator]string{
+	OpAdd: "+",
+	OpSub: "-",
+	OpMul: "*",
+	OpDiv: "/",
+	OpMod: "%",
+	OpPow: "^",
+}
+
+var operators = map[Operator]func(a, b float64) float64{
+	OpAdd: func(a, b float64) float64 { return a + b },
+	OpSub: func(a, b float64) float64 { return a - b },
+	OpMul: func(a, b float64) float64 { return a * b },
+	OpDiv: func(a, b float64) float64 { return a / b },
+	OpMod: math.Mod,
+	OpPow: math.Pow,
+}
+
+// ParseOperator returns the Operator with the given symbol (E.g. "+").
+func ParseOperator(symbol string) (Operator, error) {
+	for op, s := range operatorNames {
+		if s == symbol {
+			return op, nil
+		}
+	}
+
+	return 0, ErrUnknownOperator
+}
+
+// Apply returns the result of the operator on two values.
+func (c Operator) Apply(a, b float64) (float64, error) {
+	fn, ok := operators[c]
+	if !ok {
+		return 0, ErrUnknownOperator
+	}
+
+	return fn(a, b), nil
+}
+
+func (c Operator) String() string {
+	if name, ok := operatorNames[c]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// Function is a scalar function which may be called in a FuncExpr.
+type Function struct {
+	Args  int                          // number of arguments
+	Apply func(args []float64) float64 // computes the value at a single step
+}
+
+func unary(fn func(float64) float64) Function {
+	return Function{1, func(args []float64) float64 { return fn(args[0]) }}
+}
+
+// Functions are the functions which may be called by name in a FuncExpr.
+// Additional functions may be registered before expressions are evaluated.
+var Functions = map[string]Function{
+	"abs":   unary(math.Abs),
+	"ceil":  unary(math.Ceil),
+	"floor": unary(math.Floor),
+	"round": unary(math.Round),
+	"sqrt":  unary(math.Sqrt),
+	"exp":   unary(math.Exp),
+	"ln":    unary(math.Log),
+	"log2":  unary(math.Log2),
+	"log10": unary(math.Log10),
+	"clamp_min": {2, func(args []float64) float64 {
+		return math.Max(args[0], args[1])
+	}},
+	"clamp_max": {2, func(args []float64) float64 {
+		return math.Min(args[0], args[1])
+	}},
+}
+
+// reducers combine the non-NaN values of a group of series at a single step.
+var reducers = map[mgots.Aggregator]func(values []float64) float64{
+	mgots.AggregateSum: func(values []float64) float64 {
+		if len(values) == 0 {
+			return math.NaN()
+		}
+
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	},
+	mgots.AggregateAvg: func(values []float64) float64 {
+		if len(values) == 0 {
+			return math.NaN()
+		}
+
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	},
+	mgots.AggregateMin: func(values []float64) float64 {
+		if len(values) == 0 {
+			return math.NaN()
+		}
+
+		min := values[0]
+		for _, v := range values[1:] {
+			min = math.Min(min, v)
+		}
+		return min
+	},
+	mgots.AggregateMax: func(values []float64) float64 {
+		if len(values) == 0 {
+			return math.NaN()
+		}
+
+		max := values[0]
+		for _, v := range values[1:] {
+			max = math.Max(max, v)
+		}
+		return max
+	},
+	mgots.AggregateCount: func(values []float64) float64 {
+		return float64(len(values))
+	},
+}
+
+// combine applies fn at each step to one series from each set of arguments.
+// Sets with a single series are applied to every series of the other sets,
+// and all other sets are paired by their tags, or by the tags named in on if
+// it is not nil.
+func combine(sets [][]Series, on []string, fn func(args []float64) float64) []Series {
+	// find the first set with more than one series
+	var driver []Series
+	for _, set := range sets {
+		if len(set) == 0 {
+			return []Series{}
+		}
+
+		if len(set) > 1 && driver == nil {
+			driver = set
+		}
+	}
+
+	key := func(tags map[string]string) string {
+		if on != nil {
+			return tagKey(tags, on)
+		}
+		return tagKey(tags, tagNames(tags))
+	}
+
+	// index series by key
+	index := make([]map[string]*Series, len(sets))
+	for i, set := range sets {
+		if len(set) > 1 {
+			index[i] = make(map[string]*Series)
+			for j := range set {
+				if k := key(set[j].Tags); index[i][k] == nil {
+					index[i][k] = &set[j]
+				}
+			}
+		}
+	}
+
+	// compute one series for each series of the driver, or a single series if
+	// all sets have a single series
+	results := make([]Series, 0)
+	rows := driver
+	if rows == nil {
+		rows = sets[0]
+	}
+
+	for _, row := range rows {
+		args := make([]*Series, len(sets))
+		tags := row.Tags
+		matched := true
+		for i, set := range sets {
+			if index[i] == nil {
+				args[i] = &set[0]
+				if driver == nil {
+					tags = intersect(tags, set[0].Tags)
+				}
+				continue
+			}
+
+			if args[i] = index[i][key(row.Tags)]; args[i] == nil {
+				matched = false
+				break
+			}
+		}
+
+		if !matched {
+			continue
+		}
+
+		if on != nil {
+			tags = subset(row.Tags, on)
+		}
+
+		values := make([]float64, len(row.Values))
+		step := make([]float64, len(args))
+		for j := range values {
+			for i, arg := range args {
+				step[i] = arg.Values[j]
+			}
+			values[j] = fn(step)
+		}
+
+		results = append(results, Series{Tags: tags, Values: values})
+	}
+
+	return results
+}
+
+// tagNames returns the sorted names of the given tags.
+func tagNames(tags map[string]string) []string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// tagKey returns a string which identifies the values of the named tags.
+func tagKey(tags map[string]string, names []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + tags[name]
+	}
+
+	return strings.Join(pairs, "\x00")
+}
+
+// subset returns the named tags which are set.
+func subset(tags map[string]string, names []string) map[string]string {
+	result := make(map[string]string)
+	for _, name := range names {
+		if v, ok := tags[name]; ok {
+			result[name] = v
+		}
+	}
+
+	return result
+}
+
+// intersect returns the tags with the same value in a and b.
+func intersect(a map[string]string, b map[string]string) map[string]string {
+	result := make(map[string]string)
+	for name, v := range a {
+		if w, ok := b[name]; ok && v == w {
+			result[name] = v
+		}
+	}
+
+	return result
+}
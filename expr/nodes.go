@@ -0,0 +1,285 @@
+package expr
+
+import (
+	"fmt"
+	"github.com/cavaliercoder/mgots"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RefExpr is a reference to a single series. The computed series has the
+// tags of the referenced series.
+type RefExpr struct {
+	SeriesId interface{}
+}
+
+// Ref returns an expression which references a single series.
+func Ref(seriesId interface{}) *RefExpr {
+	return &RefExpr{seriesId}
+}
+
+func (c *RefExpr) Eval(ctx *Context) ([]Series, error) {
+	info, err := ctx.Collection.Info(c.SeriesId)
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := ctx.Collection.Resample(c.SeriesId, ctx.MinTime, ctx.MaxTime, ctx.Step, ctx.Fill)
+	if err != nil {
+		return nil, err
+	}
+
+	// align values by timestamp
+	timestamps := ctx.Timestamps()
+	values := nan(len(timestamps))
+	j := 0
+	for _, point := range points {
+		for j < len(timestamps) && timestamps[j].Before(point.Timestamp()) {
+			j++
+		}
+
+		if j < len(timestamps) && timestamps[j].Equal(point.Timestamp()) {
+			if values[j], err = point.Float64(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return []Series{{Tags: info.Tags, Values: values}}, nil
+}
+
+func (c *RefExpr) String() string {
+	id := fmt.Sprint(c.SeriesId)
+	if oid, ok := c.SeriesId.(bson.ObjectId); ok {
+		id = oid.Hex()
+	}
+
+	return fmt.Sprintf("series(%s)", strconv.Quote(id))
+}
+
+// SelectExpr selects every series which has all of the given tags.
+type SelectExpr struct {
+	Tags map[string]string
+}
+
+// Select returns an expression which selects every series with all of the
+// given tags.
+func Select(tags map[string]string) *SelectExpr {
+	return &SelectExpr{tags}
+}
+
+func (c *SelectExpr) Eval(ctx *Context) ([]Series, error) {
+	ids, err := ctx.Collection.FindSeries(c.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Series, 0, len(ids))
+	for _, id := range ids {
+		series, err := Ref(id).Eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, series...)
+	}
+
+	return results, nil
+}
+
+func (c *SelectExpr) String() string {
+	names := tagNames(c.Tags)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(c.Tags[name])
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// ScalarExpr is a constant value at every step.
+type ScalarExpr struct {
+	Value float64
+}
+
+// Scalar returns an expression with a constant value.
+func Scalar(v float64) *ScalarExpr {
+	return &ScalarExpr{v}
+}
+
+func (c *ScalarExpr) Eval(ctx *Context) ([]Series, error) {
+	values := make([]float64, len(ctx.Timestamps()))
+	for i := range values {
+		values[i] = c.Value
+	}
+
+	return []Series{{Values: values}}, nil
+}
+
+func (c *ScalarExpr) String() string {
+	return strconv.FormatFloat(c.Value, 'g', -1, 64)
+}
+
+// BinaryExpr applies an arithmetic operator to the values of two
+// expressions at each step.
+//
+// If either side computes a single series, it is applied to every series on
+// the other side. Otherwise, series are paired by their tags, or only by the
+// tags named in On, and series without a pair are dropped.
+type BinaryExpr struct {
+	Op  Operator
+	LHS Expr
+	RHS Expr
+	On  []string
+}
+
+// Binary returns an expression which applies an operator to two expressions.
+func Binary(op Operator, lhs Expr, rhs Expr) *BinaryExpr {
+	return &BinaryExpr{Op: op, LHS: lhs, RHS: rhs}
+}
+
+func (c *BinaryExpr) Eval(ctx *Context) ([]Series, error) {
+	fn, ok := operators[c.Op]
+	if !ok {
+		return nil, ErrUnknownOperator
+	}
+
+	lhs, err := c.LHS.Eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rhs, err := c.RHS.Eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return combine([][]Series{lhs, rhs}, c.On, func(args []float64) float64 {
+		return fn(args[0], args[1])
+	}), nil
+}
+
+func (c *BinaryExpr) String() string {
+	on := ""
+	if c.On != nil {
+		on = " on (" + strings.Join(c.On, ", ") + ")"
+	}
+
+	return fmt.Sprintf("(%s %s%s %s)", c.LHS, c.Op, on, c.RHS)
+}
+
+// AggregateExpr reduces the series computed by an expression into one series
+// for each distinct combination of values of the tags named in By, or into a
+// single series if By is empty. NaN values are ignored.
+type AggregateExpr struct {
+	Aggregator mgots.Aggregator
+	By         []string
+	Expr       Expr
+}
+
+// Aggregate returns an expression which reduces the series of an expression
+// grouped by the given tags. Only the sum, avg, min, max and count
+// aggregators are supported.
+func Aggregate(aggregator mgots.Aggregator, expr Expr, by ...string) *AggregateExpr {
+	return &AggregateExpr{Aggregator: aggregator, By: by, Expr: expr}
+}
+
+func (c *AggregateExpr) Eval(ctx *Context) ([]Series, error) {
+	reduce, ok := reducers[c.Aggregator]
+	if !ok {
+		return nil, mgots.ErrUnknownAggregator
+	}
+
+	series, err := c.Expr.Eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// group series by the named tags
+	keys := make([]string, 0)
+	groups := make(map[string][]Series)
+	for _, s := range series {
+		key := tagKey(s.Tags, c.By)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], s)
+	}
+
+	// reduce each group at each step
+	n := len(ctx.Timestamps())
+	results := make([]Series, len(keys))
+	for i, key := range keys {
+		group := groups[key]
+		values := make([]float64, n)
+		args := make([]float64, 0, len(group))
+		for j := range values {
+			args = args[:0]
+			for _, s := range group {
+				if !math.IsNaN(s.Values[j]) {
+					args = append(args, s.Values[j])
+				}
+			}
+			values[j] = reduce(args)
+		}
+
+		results[i] = Series{Tags: subset(group[0].Tags, c.By), Values: values}
+	}
+
+	return results, nil
+}
+
+func (c *AggregateExpr) String() string {
+	by := ""
+	if len(c.By) > 0 {
+		by = " by (" + strings.Join(c.By, ", ") + ")"
+	}
+
+	return fmt.Sprintf("%s%s (%s)", c.Aggregator, by, c.Expr)
+}
+
+// FuncExpr applies a function to the values of its arguments at each step.
+// Arguments are paired as per BinaryExpr.
+type FuncExpr struct {
+	Name string
+	Args []Expr
+}
+
+// Func returns an expression which applies the named function from Functions
+// to the given arguments.
+func Func(name string, args ...Expr) *FuncExpr {
+	return &FuncExpr{Name: name, Args: args}
+}
+
+func (c *FuncExpr) Eval(ctx *Context) ([]Series, error) {
+	fn, ok := Functions[c.Name]
+	if !ok {
+		return nil, ErrUnknownFunction
+	}
+
+	if len(c.Args) != fn.Args {
+		return nil, ErrArgumentCount
+	}
+
+	args := make([][]Series, len(c.Args))
+	for i, arg := range c.Args {
+		series, err := arg.Eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = series
+	}
+
+	return combine(args, nil, fn.Apply), nil
+}
+
+func (c *FuncExpr) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+
+	return fmt.Sprintf("%s(%s)", c.Name, strings.Join(args, ", "))
+}
@@ -0,0 +1,341 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Comparison identifies the operator of a filter Condition.
+type Comparison int
+
+const (
+	CompareEq  Comparison = iota // value equals the operand
+	CompareNe                    // value does not equal the operand
+	CompareGt                    // value is greater than the operand
+	CompareGte                   // value is greater than or equal to the operand
+	CompareLt                    // value is less than the operand
+	CompareLte                   // value is less than or equal to the operand
+)
+
+var comparisonNames = map[Comparison]string{
+	CompareEq:  "==",
+	CompareNe:  "!=",
+	CompareGt:  ">",
+	CompareGte: ">=",
+	CompareLt:  "<",
+	CompareLte: "<=",
+}
+
+// comparisonOperators are the MongoDB query operators of each comparison.
+var comparisonOperators = map[Comparison]string{
+	CompareEq:  "$eq",
+	CompareNe:  "$ne",
+	CompareGt:  "$gt",
+	CompareGte: "$gte",
+	CompareLt:  "$lt",
+	CompareLte: "$lte",
+}
+
+// Errors
+var ErrUnknownComparison = errors.New("Unknown comparison operator")
+
+// ParseComparison returns the Comparison with the given symbol (E.g. ">=").
+func ParseComparison(s string) (Comparison, error) {
+	for op, name := range comparisonNames {
+		if name == s {
+			return op, nil
+		}
+	}
+
+	return 0, ErrUnknownComparison
+}
+
+func (c Comparison) String() string {
+	return comparisonNames[c]
+}
+
+// Condition compares a field of the value of each data point with an
+// operand. Fields of embedded documents are named with dotted paths (E.g.
+// "request.status") and an empty field compares the value itself. Missing
+// fields compare as null and array fields match if any element matches.
+//
+// As in MongoDB, values of different types are never ordered, so only
+// CompareNe matches a value of a different type to the operand. All numeric
+// types compare as numbers.
+type Condition struct {
+	Field string
+	Op    Comparison
+	Value interface{}
+}
+
+// Filter matches the data points which satisfy all of its conditions.
+type Filter []Condition
+
+// RangeWhere returns the data points between minTime and maxTime which match
+// the given filter.
+//
+// The filter is applied by MongoDB in an aggregation over the page arrays, so
+// only matching data points are returned by the server. If the server does
+// not support the aggregation, the range is fetched and the filter is applied
+// by the client.
+func (c *NonperiodicCollection) RangeWhere(seriesId interface{}, minTime time.Time, maxTime time.Time, filter Filter) (DataPoints, error) {
+	match, err := filter.query("value")
+	if err != nil {
+		return nil, wrapError("RangeWhere", seriesId, err)
+	}
+
+	points, err := c.rangeWhere(seriesId, minTime, maxTime, match)
+	if err == nil {
+		return points, nil
+	}
+
+	var queryErr *mgo.QueryError
+	if !errors.As(err, &queryErr) || errorKind(err) == ErrorKindTransient {
+		return nil, wrapError("RangeWhere", seriesId, newError(err, "Error filtering time series pages"))
+	}
+
+	// fall back to filtering the whole range
+	points, err = c.Range(seriesId, minTime, maxTime)
+	if err != nil {
+		return nil, wrapError("RangeWhere", seriesId, err)
+	}
+
+	points, err = filter.apply(points)
+	if err != nil {
+		return nil, wrapError("RangeWhere", seriesId, err)
+	}
+
+	return points, nil
+}
+
+// rangeWhere unwinds the entries of each page in the range and returns those
+// which match the given query on their value.
+func (c *NonperiodicCollection) rangeWhere(seriesId interface{}, minTime time.Time, maxTime time.Time, match bson.M) (DataPoints, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"seriesid":  seriesId,
+			"starttime": bson.M{"$lte": maxTime},
+			"endtime":   bson.M{"$gte": minTime},
+		}},
+		{"$project": bson.M{
+			"starttime":  1,
+			"timestamps": 1,
+			"values":     1,
+			"float64s":   1,
+		}},
+		{"$unwind": bson.M{
+			"path":              "$timestamps",
+			"includeArrayIndex": "slot",
+		}},
+		{"$match": bson.M{
+			"timestamps": bson.M{"$gte": minTime, "$lte": maxTime},
+		}},
+		{"$project": bson.M{
+			"starttime": 1,
+			"slot":      1,
+			"timestamp": "$timestamps",
+			"value": bson.M{"$ifNull": []interface{}{
+				bson.M{"$arrayElemAt": []interface{}{"$values", "$slot"}},
+				bson.M{"$arrayElemAt": []interface{}{"$float64s", "$slot"}},
+			}},
+		}},
+		{"$match": match},
+
+		// entries are stored in reverse order
+		{"$sort": bson.D{
+			{Name: "timestamp", Value: 1},
+			{Name: "starttime", Value: 1},
+			{Name: "slot", Value: -1},
+		}},
+	}
+
+	type pageEntry struct {
+		Timestamp time.Time
+		Value     bson.Raw
+	}
+
+	var entry pageEntry
+	results := DataPoints{}
+	iter := c.DBCollection.Pipe(pipeline).Iter()
+	for iter.Next(&entry) {
+		results = append(results, &dataPoint{
+			timestamp: entry.Timestamp,
+			value:     entry.Value,
+		})
+		entry = pageEntry{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// query returns a MongoDB query which matches the filter on the given field.
+func (c Filter) query(field string) (bson.M, error) {
+	query := bson.M{}
+	conditions := make([]interface{}, 0, len(c))
+	for _, condition := range c {
+		op, ok := comparisonOperators[condition.Op]
+		if !ok {
+			return nil, ErrUnknownComparison
+		}
+
+		path := field
+		if condition.Field != "" {
+			path += "." + condition.Field
+		}
+
+		conditions = append(conditions, bson.M{path: bson.M{op: condition.Value}})
+	}
+
+	if len(conditions) > 0 {
+		query["$and"] = conditions
+	}
+
+	return query, nil
+}
+
+// apply returns the data points which match the filter.
+func (c Filter) apply(points DataPoints) (DataPoints, error) {
+	results := DataPoints{}
+	for _, point := range points {
+		var v interface{}
+		if err := point.GetValue(&v); err != nil {
+			return nil, err
+		}
+
+		if c.match(v) {
+			results = append(results, point)
+		}
+	}
+
+	return results, nil
+}
+
+// match returns true if the given decoded value satisfies every condition.
+func (c Filter) match(v interface{}) bool {
+	for _, condition := range c {
+		if !condition.match(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c Condition) match(v interface{}) bool {
+	// as in MongoDB, values match CompareNe if they do not match CompareEq
+	if c.Op == CompareNe {
+		eq := c
+		eq.Op = CompareEq
+		return !eq.match(v)
+	}
+
+	if c.Field != "" {
+		for _, name := range strings.Split(c.Field, ".") {
+			doc, ok := v.(bson.M)
+			if !ok {
+				v = nil
+				break
+			}
+			v = doc[name]
+		}
+	}
+
+	// array fields match if any element matches, as in MongoDB
+	if a, ok := v.([]interface{}); ok {
+		for _, e := range a {
+			if c.compare(e) {
+				return true
+			}
+		}
+	}
+
+	return c.compare(v)
+}
+
+// compare applies the comparison, other than CompareNe, to a single value.
+func (c Condition) compare(v interface{}) bool {
+	order, ok := compareValues(v, c.Value)
+	switch c.Op {
+	case CompareEq:
+		return ok && order == 0
+	case CompareGt:
+		return ok && order > 0
+	case CompareGte:
+		return ok && order >= 0
+	case CompareLt:
+		return ok && order < 0
+	case CompareLte:
+		return ok && order <= 0
+	}
+
+	return false
+}
+
+// compareValues returns -1, 0 or 1 if a is less than, equal to or greater
+// than b, or false if the values are of different types.
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, a == nil && b == nil
+	}
+
+	if x, ok := toFloat64(a); ok {
+		y, ok := toFloat64(b)
+		return compareFloat64(x, y), ok
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bson.ObjectId:
+		if y, ok := b.(bson.ObjectId); ok {
+			return strings.Compare(string(x), string(y)), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case y:
+				return -1, true
+			}
+			return 1, true
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			}
+			return 0, true
+		}
+	default:
+		if reflect.DeepEqual(a, b) {
+			return 0, true
+		}
+	}
+
+	return 0, false
+}
+
+func compareFloat64(x float64, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+
+	return 0
+}
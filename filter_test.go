@@ -0,0 +1,151 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestRangeWhere(t *testing.T) {
+	database := DBConnect()
+	name := "test_range_where"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add a request event every minute
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	statuses := []int{200, 503, 404, 500, 200, 502, 301, 200}
+	paths := []string{"/", "/api", "/api", "/", "/api", "/", "/api", "/"}
+	for i, status := range statuses {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Minute), bson.M{
+			"status": status,
+			"path":   paths[i],
+		})
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	tests := []struct {
+		filter   Filter
+		expected []int // minutes of the matching events
+	}{
+		{Filter{{"status", CompareGte, 500}}, []int{1, 3, 5}},
+		{Filter{{"status", CompareGte, 500}, {"path", CompareEq, "/api"}}, []int{1}},
+		{Filter{{"status", CompareNe, 200}, {"status", CompareLt, 500}}, []int{2, 6}},
+		{Filter{{"status", CompareGt, "500"}}, []int{}},
+		{Filter{{"missing", CompareEq, nil}}, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{Filter{}, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+	}
+
+	maxTime := startTime.Add(time.Hour)
+	for _, test := range tests {
+		points, err := collection.RangeWhere(seriesId, startTime, maxTime, test.filter)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		// the client side filter must agree with the server
+		all, err := collection.Range(seriesId, startTime, maxTime)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		filtered, err := test.filter.apply(all)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		for _, results := range []DataPoints{points, filtered} {
+			if len(results) != len(test.expected) {
+				t.Errorf("Expected %d events matching %v, got %d", len(test.expected), test.filter, len(results))
+				continue
+			}
+
+			for i, point := range results {
+				expected := startTime.Add(time.Duration(test.expected[i]) * time.Minute)
+				if !point.Timestamp().Equal(expected) {
+					t.Errorf("Expected event %d matching %v at %v, got %v", i, test.filter, expected, point.Timestamp())
+				}
+			}
+		}
+	}
+
+	// the range is applied with the filter
+	points, err := collection.RangeWhere(seriesId, startTime.Add(2*time.Minute), startTime.Add(4*time.Minute), Filter{{"status", CompareGte, 500}})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(points) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(points))
+	}
+
+	var event struct{ Status int }
+	if err := points[0].GetValue(&event); err != nil || event.Status != 500 {
+		t.Errorf("Expected status 500, got %d (%v)", event.Status, err)
+	}
+
+	// unknown operators are invalid
+	_, err = collection.RangeWhere(seriesId, startTime, maxTime, Filter{{"status", Comparison(-1), 500}})
+	if !errors.Is(err, ErrUnknownComparison) || !errors.Is(err, ErrorKindInvalid) {
+		t.Errorf("Expected ErrUnknownComparison, got %v", err)
+	}
+}
+
+func TestRangeWhereFloat64(t *testing.T) {
+	database := DBConnect()
+	name := "test_range_where_float64"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add a packed value every minute
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateFloat64Series(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i := 0; i < 100; i++ {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Minute), float64(i))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	points, err := collection.RangeWhere(seriesId, startTime, startTime.Add(time.Hour), Filter{{"", CompareGte, 55}})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(points) != 6 {
+		t.Fatalf("Expected 6 data points, got %d", len(points))
+	}
+
+	for i, point := range points {
+		v, err := point.Float64()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if v != float64(55+i) {
+			t.Errorf("Expected %v, got %v", float64(55+i), v)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+// Package grafana implements the HTTP contract of the Grafana JSON (formerly
+// SimpleJSON) datasource on top of an mgots Collection, so Grafana panels can
+// query time series without a custom plugin.
+//
+// Series are addressed in Grafana by target names. By default ObjectId series
+// IDs are formatted as hex strings and all other IDs are formatted with
+// fmt.Sprint.
+package grafana
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cavaliercoder/mgots"
+	"github.com/cavaliercoder/mgots/expr"
+	"github.com/cavaliercoder/mgots/query"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler serves the /, /search, /query and /annotations endpoints expected
+// by the Grafana JSON datasource.
+type Handler struct {
+	Collection mgots.Collection
+
+	// Aggregator is used to downsample series for time series panels when no
+	// aggregator is specified in the target data. Defaults to AggregateAvg.
+	Aggregator mgots.Aggregator
+
+	// FormatSeriesId converts a series ID into a Grafana target name.
+	FormatSeriesId func(seriesId interface{}) string
+
+	// ParseSeriesId converts a Grafana target name into a series ID.
+	ParseSeriesId func(target string) (interface{}, error)
+
+	mux *http.ServeMux
+}
+
+type timeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type queryTarget struct {
+	Target string `json:"target"`
+	RefId  string `json:"refId"`
+	Type   string `json:"type"`
+	Data   struct {
+		Aggregate string `json:"aggregate"`
+		Query     bool   `json:"query"`
+	} `json:"data"`
+}
+
+type queryRequest struct {
+	Range         timeRange     `json:"range"`
+	IntervalMs    int64         `json:"intervalMs"`
+	MaxDataPoints int64         `json:"maxDataPoints"`
+	Targets       []queryTarget `json:"targets"`
+}
+
+type timeSeries struct {
+	Target     string       `json:"target"`
+	DataPoints [][2]float64 `json:"datapoints"`
+}
+
+type tableColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+type table struct {
+	Type    string          `json:"type"`
+	Columns []tableColumn   `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+type annotationRequest struct {
+	Range      timeRange `json:"range"`
+	Annotation struct {
+		Name       string `json:"name"`
+		Datasource string `json:"datasource"`
+		Enable     bool   `json:"enable"`
+		IconColor  string `json:"iconColor"`
+		Query      string `json:"query"`
+	} `json:"annotation"`
+}
+
+type annotation struct {
+	Annotation interface{} `json:"annotation"`
+	Time       int64       `json:"time"`
+	Title      string      `json:"title"`
+	Tags       []string    `json:"tags"`
+	Text       string      `json:"text"`
+}
+
+// NewHandler returns a Handler which serves the series in the given
+// collection.
+func NewHandler(collection mgots.Collection) *Handler {
+	c := &Handler{
+		Collection:     collection,
+		Aggregator:     mgots.AggregateAvg,
+		FormatSeriesId: formatSeriesId,
+		ParseSeriesId:  parseSeriesId,
+	}
+
+	c.mux = http.NewServeMux()
+	c.mux.HandleFunc("/", c.handleTest)
+	c.mux.HandleFunc("/search", c.handleSearch)
+	c.mux.HandleFunc("/query", c.handleQuery)
+	c.mux.HandleFunc("/annotations", c.handleAnnotations)
+
+	return c
+}
+
+func (c *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "accept, content-type")
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	c.mux.ServeHTTP(w, r)
+}
+
+// handleTest responds to the connection test performed by Grafana when the
+// datasource is saved.
+func (c *Handler) handleTest(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleSearch returns the names of all series in the collection which
+// contain the requested target string.
+func (c *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Target string `json:"target"`
+	}
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	series, err := c.Collection.ListSeries()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	names := make([]string, 0, len(series))
+	for _, seriesId := range series {
+		name := c.FormatSeriesId(seriesId)
+		if strings.Contains(name, req.Target) {
+			names = append(names, name)
+		}
+	}
+
+	writeResponse(w, names)
+}
+
+// handleQuery returns the data points of each requested target, aggregated
+// to the requested interval for time series panels or raw for table panels.
+func (c *Handler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var req queryRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	if interval <= 0 && req.MaxDataPoints > 0 {
+		interval = req.Range.To.Sub(req.Range.From) / time.Duration(req.MaxDataPoints)
+	}
+
+	results := make([]interface{}, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.Data.Query {
+			series, ok := c.runQuery(w, target.Target, req.Range, interval)
+			if !ok {
+				return
+			}
+
+			results = append(results, series...)
+			continue
+		}
+
+		seriesId, err := c.ParseSeriesId(target.Target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		aggregator := c.Aggregator
+		if target.Data.Aggregate != "" {
+			aggregator, err = mgots.ParseAggregator(target.Data.Aggregate)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		var points mgots.DataPoints
+		if target.Type == "table" || interval <= 0 {
+			points, err = c.Collection.Range(seriesId, req.Range.From, req.Range.To)
+		} else {
+			points, err = c.Collection.Aggregate(seriesId, req.Range.From, req.Range.To, interval, aggregator)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var result interface{}
+		if target.Type == "table" {
+			result, err = newTable(points)
+		} else {
+			result, err = newTimeSeries(target.Target, points)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		results = append(results, result)
+	}
+
+	writeResponse(w, results)
+}
+
+// runQuery executes a target written in the mgots query language and returns
+// a time series for each resulting series. The range and step of the request
+// apply unless they are given in the query. If the query cannot be run, an
+// error response is written and false is returned.
+func (c *Handler) runQuery(w http.ResponseWriter, target string, r timeRange, interval time.Duration) ([]interface{}, bool) {
+	q, err := query.Parse(target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if q.From == nil {
+		q.From = &query.Time{Time: r.From}
+	}
+
+	if q.To == nil {
+		q.To = &query.Time{Time: r.To}
+	}
+
+	if q.Step == 0 && interval > 0 {
+		q.Step = interval
+	}
+
+	p, err := q.Plan(time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	result, err := p.Execute(c.Collection)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return nil, false
+	}
+
+	series := make([]interface{}, len(result.Series))
+	for i, s := range result.Series {
+		name := target
+		if len(s.Tags) > 0 {
+			name = expr.Select(s.Tags).String()
+		}
+
+		// omit steps without a finite value, which cannot be encoded as JSON
+		ts := &timeSeries{Target: name, DataPoints: make([][2]float64, 0, len(s.Values))}
+		for j, v := range s.Values {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				ts.DataPoints = append(ts.DataPoints, [2]float64{v, float64(unixMilli(result.Timestamps[j]))})
+			}
+		}
+		series[i] = ts
+	}
+
+	return series, true
+}
+
+// errorStatus returns the HTTP status code of an error returned while
+// evaluating a query.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, mgots.ErrorKindNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, mgots.ErrorKindInvalid), errors.Is(err, mgots.ErrInvalidInterval),
+		errors.Is(err, expr.ErrUnknownOperator), errors.Is(err, expr.ErrUnknownFunction), errors.Is(err, expr.ErrArgumentCount):
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+// handleAnnotations returns an annotation for each data point in the series
+// named by the annotation query.
+func (c *Handler) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	var req annotationRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	seriesId, err := c.ParseSeriesId(req.Annotation.Query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	points, err := c.Collection.Range(seriesId, req.Range.From, req.Range.To)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	annotations := make([]annotation, len(points))
+	for i, point := range points {
+		var v interface{}
+		if err := point.GetValue(&v); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		annotations[i] = annotation{
+			Annotation: req.Annotation,
+			Time:       unixMilli(point.Timestamp()),
+			Title:      req.Annotation.Name,
+			Tags:       []string{},
+			Text:       fmt.Sprint(v),
+		}
+	}
+
+	writeResponse(w, annotations)
+}
+
+func newTimeSeries(target string, points mgots.DataPoints) (*timeSeries, error) {
+	series := &timeSeries{
+		Target:     target,
+		DataPoints: make([][2]float64, 0, len(points)),
+	}
+
+	// omit null and infinite values, which cannot be encoded as JSON
+	for _, point := range points {
+		v, err := point.Float64()
+		if err != nil {
+			return nil, err
+		}
+
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			series.DataPoints = append(series.DataPoints, [2]float64{v, float64(unixMilli(point.Timestamp()))})
+		}
+	}
+
+	return series, nil
+}
+
+func newTable(points mgots.DataPoints) (*table, error) {
+	t := &table{
+		Type: "table",
+		Columns: []tableColumn{
+			{Text: "Time", Type: "time"},
+			{Text: "Value", Type: "string"},
+		},
+		Rows: make([][]interface{}, len(points)),
+	}
+
+	for i, point := range points {
+		var v interface{}
+		if err := point.GetValue(&v); err != nil {
+			return nil, err
+		}
+
+		t.Rows[i] = []interface{}{unixMilli(point.Timestamp()), v}
+	}
+
+	return t, nil
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func formatSeriesId(seriesId interface{}) string {
+	if id, ok := seriesId.(bson.ObjectId); ok {
+		return id.Hex()
+	}
+
+	return fmt.Sprint(seriesId)
+}
+
+func parseSeriesId(target string) (interface{}, error) {
+	if bson.IsObjectIdHex(target) {
+		return bson.ObjectIdHex(target), nil
+	}
+
+	return target, nil
+}
@@ -0,0 +1,211 @@
+package grafana
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cavaliercoder/mgots"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+const (
+	url          = "mongodb://localhost/mgots"
+	testDb       = "mgots_grafana_test"
+	testPageSize = 4096
+)
+
+func TestMain(m *testing.M) {
+	// Connect to MongoDB and cleanup previous data
+	database := DBConnect()
+	database.DropDatabase()
+
+	os.Exit(m.Run())
+}
+
+func DBConnect() *mgo.Database {
+	session, err := mgo.Dial(url)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to %s with: %s", url, err.Error())
+		os.Exit(1)
+	}
+
+	session.SetSafe(&mgo.Safe{W: 1})
+
+	return session.DB(testDb)
+}
+
+func post(t *testing.T, handler http.Handler, path string, body interface{}, v interface{}) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", path, bytes.NewReader(b)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 from %s, got %d: %s", path, w.Code, w.Body.String())
+	}
+
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("Error decoding response from %s: %s", path, err.Error())
+	}
+}
+
+func TestGrafana(t *testing.T) {
+	collection, err := mgots.NewNonperiodicCollection(DBConnect(), "test_grafana", testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Create a series with one value per minute for an hour
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().Truncate(time.Hour).Add(-time.Hour)
+	if err = collection.CreateSeries(seriesId, startTime); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i := 0; i < 60; i++ {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Minute), float64(i))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	handler := NewHandler(collection)
+
+	// search for the series
+	var names []string
+	post(t, handler, "/search", map[string]string{"target": ""}, &names)
+	if len(names) != 1 || names[0] != seriesId.Hex() {
+		t.Errorf("Expected search to return [%s], got %v", seriesId.Hex(), names)
+	}
+
+	// query the series in ten minute intervals
+	var series []timeSeries
+	post(t, handler, "/query", map[string]interface{}{
+		"range": map[string]interface{}{
+			"from": startTime,
+			"to":   startTime.Add(time.Hour),
+		},
+		"intervalMs": 600000,
+		"targets": []map[string]interface{}{
+			{"target": seriesId.Hex(), "refId": "A", "type": "timeserie"},
+		},
+	}, &series)
+
+	if len(series) != 1 {
+		t.Fatalf("Expected 1 time series, got %d", len(series))
+	}
+
+	if len(series[0].DataPoints) != 6 {
+		t.Fatalf("Expected 6 data points, got %d", len(series[0].DataPoints))
+	}
+
+	for i, point := range series[0].DataPoints {
+		expected := float64(i*10) + 4.5
+		if point[0] != expected {
+			t.Errorf("Expected data point %d to be %v, got %v", i, expected, point[0])
+		}
+	}
+
+	// query the series by tag with the query language
+	if err = collection.SetTags(seriesId, map[string]string{"metric": "test"}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	series = nil
+	post(t, handler, "/query", map[string]interface{}{
+		"range": map[string]interface{}{
+			"from": startTime,
+			"to":   startTime.Add(time.Hour),
+		},
+		"intervalMs": 600000,
+		"targets": []map[string]interface{}{
+			{"target": `{metric="test"} * 2`, "refId": "A", "type": "timeserie", "data": map[string]interface{}{"query": true}},
+		},
+	}, &series)
+
+	if len(series) != 1 {
+		t.Fatalf("Expected 1 time series, got %d", len(series))
+	}
+
+	if series[0].Target != `{metric="test"}` || len(series[0].DataPoints) != 6 {
+		t.Fatalf("Expected 6 data points in %s, got %d", series[0].Target, len(series[0].DataPoints))
+	}
+
+	for i, point := range series[0].DataPoints {
+		expected := (float64(i*10) + 4.5) * 2
+		if point[0] != expected {
+			t.Errorf("Expected query data point %d to be %v, got %v", i, expected, point[0])
+		}
+	}
+
+	// steps divided by zero are omitted rather than encoded as infinity
+	series = nil
+	post(t, handler, "/query", map[string]interface{}{
+		"range": map[string]interface{}{
+			"from": startTime,
+			"to":   startTime.Add(time.Hour),
+		},
+		"intervalMs": 600000,
+		"targets": []map[string]interface{}{
+			{"target": `{metric="test"} / ({metric="test"} * 0)`, "refId": "A", "type": "timeserie", "data": map[string]interface{}{"query": true}},
+		},
+	}, &series)
+
+	if len(series) != 1 || len(series[0].DataPoints) != 0 {
+		t.Fatalf("Expected 1 time series without data points, got %+v", series)
+	}
+
+	// annotate each value
+	var annotations []annotation
+	post(t, handler, "/annotations", map[string]interface{}{
+		"range": map[string]interface{}{
+			"from": startTime,
+			"to":   startTime.Add(time.Hour),
+		},
+		"annotation": map[string]interface{}{
+			"name":  "test",
+			"query": seriesId.Hex(),
+		},
+	}, &annotations)
+
+	if len(annotations) != 60 {
+		t.Errorf("Expected 60 annotations, got %d", len(annotations))
+	}
+
+	// null values are omitted
+	nullId := bson.NewObjectId()
+	if err = collection.CreateSeries(nullId, startTime); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i, v := range []interface{}{1.0, nil, 3.0} {
+		err = collection.Append(nullId, startTime.Add(time.Duration(i)*time.Minute), v)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	series = nil
+	post(t, handler, "/query", map[string]interface{}{
+		"range": map[string]interface{}{
+			"from": startTime,
+			"to":   startTime.Add(time.Hour),
+		},
+		"intervalMs": 60000,
+		"targets": []map[string]interface{}{
+			{"target": nullId.Hex(), "refId": "A", "type": "timeserie"},
+		},
+	}, &series)
+
+	if len(series) != 1 || len(series[0].DataPoints) != 2 {
+		t.Fatalf("Expected 2 data points without nulls, got %v", series)
+	}
+}
@@ -0,0 +1,183 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+// First returns the earliest data point in a series, or nil if the series is
+// empty.
+func (c *NonperiodicCollection) First(seriesId interface{}) (DataPoint, error) {
+	page, err := c.findPage(bson.M{"seriesid": seriesId}, "starttime")
+	if err != nil {
+		return nil, wrapError("First", seriesId, err)
+	}
+
+	if page == nil {
+		return nil, wrapError("First", seriesId, c.checkSeries(seriesId))
+	}
+
+	for i := len(page.Timestamps) - 1; i >= 0; i-- {
+		if !page.Timestamps[i].IsZero() {
+			return page.point(i), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Before returns the most recent data point at or before the given time, or
+// nil if no such data point exists. This is the value of the series as of the
+// given time.
+func (c *NonperiodicCollection) Before(seriesId interface{}, t time.Time) (DataPoint, error) {
+	point, err := c.before(seriesId, t)
+	if err != nil {
+		return nil, wrapError("Before", seriesId, err)
+	}
+
+	return point, nil
+}
+
+func (c *NonperiodicCollection) before(seriesId interface{}, t time.Time) (DataPoint, error) {
+	// The end time of each page is the first entry of the next page, so the
+	// first page ending after t contains the entry. If no page ends after t,
+	// the entry is the last in the series.
+	page, err := c.findPage(bson.M{
+		"seriesid": seriesId,
+		"endtime":  bson.M{"$gt": t},
+	}, "starttime")
+	if err == nil && page == nil {
+		page, err = c.findPage(bson.M{"seriesid": seriesId}, "-starttime")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if page == nil {
+		return nil, c.checkSeries(seriesId)
+	}
+
+	// pages are stored in reverse order
+	for i := 0; i < len(page.Timestamps); i++ {
+		timestamp := page.Timestamps[i]
+		if !timestamp.IsZero() && !timestamp.After(t) {
+			return page.point(i), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// After returns the earliest data point at or after the given time, or nil if
+// no such data point exists.
+func (c *NonperiodicCollection) After(seriesId interface{}, t time.Time) (DataPoint, error) {
+	point, err := c.after(seriesId, t)
+	if err != nil {
+		return nil, wrapError("After", seriesId, err)
+	}
+
+	return point, nil
+}
+
+func (c *NonperiodicCollection) after(seriesId interface{}, t time.Time) (DataPoint, error) {
+	// The start time of each page is the last entry of the previous page, so
+	// the last page starting before t contains the entry. If no page starts
+	// before t, the entry is the first in the series.
+	page, err := c.findPage(bson.M{
+		"seriesid":  seriesId,
+		"starttime": bson.M{"$lt": t},
+	}, "-starttime")
+	if err == nil && page == nil {
+		page, err = c.findPage(bson.M{"seriesid": seriesId}, "starttime")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if page == nil {
+		return nil, c.checkSeries(seriesId)
+	}
+
+	for i := len(page.Timestamps) - 1; i >= 0; i-- {
+		timestamp := page.Timestamps[i]
+		if !timestamp.IsZero() && !timestamp.Before(t) {
+			return page.point(i), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Nearest returns the data point closest in time to the given time, or nil if
+// the series is empty. If two data points are equally close, the earlier is
+// returned.
+func (c *NonperiodicCollection) Nearest(seriesId interface{}, t time.Time) (DataPoint, error) {
+	before, err := c.before(seriesId, t)
+	if err != nil {
+		return nil, wrapError("Nearest", seriesId, err)
+	}
+
+	if before != nil && before.Timestamp().Equal(t) {
+		return before, nil
+	}
+
+	after, err := c.after(seriesId, t)
+	if err != nil {
+		return nil, wrapError("Nearest", seriesId, err)
+	}
+
+	if before == nil {
+		return after, nil
+	}
+
+	if after == nil || t.Sub(before.Timestamp()) <= after.Timestamp().Sub(t) {
+		return before, nil
+	}
+
+	return after, nil
+}
+
+// findPage returns the first page matching the given query in the given sort
+// order, or nil if no page matches.
+func (c *NonperiodicCollection) findPage(query bson.M, sort string) (*dataPage, error) {
+	var page dataPage
+	err := c.DBCollection.Find(query).Select(bson.M{"padding": 0}).Sort(sort).One(&page)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nil
+		}
+
+		return nil, newError(err, "Error searching for time series page")
+	}
+
+	return &page, nil
+}
+
+// checkSeries returns ErrSeriesNotFound if the given series does not exist.
+func (c *NonperiodicCollection) checkSeries(seriesId interface{}) error {
+	count, err := c.DBCursorCollection.FindId(seriesId).Count()
+	if err != nil {
+		return newError(err, "Error searching for time series")
+	}
+
+	if count == 0 {
+		return ErrSeriesNotFound
+	}
+
+	return nil
+}
+
+// point returns the data point stored in the given slot of the page.
+func (c *dataPage) point(i int) DataPoint {
+	if c.Float64s != nil {
+		return newFloat64DataPoint(c.Timestamps[i], c.Float64s[i])
+	}
+
+	return &dataPoint{
+		timestamp: c.Timestamps[i],
+		value:     c.Values[i],
+	}
+}
@@ -0,0 +1,107 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	database := DBConnect()
+	name := "test_lookup"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add a value every minute across many pages
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	entryCount := 500
+	timestamp := func(i int) time.Time {
+		return startTime.Add(time.Duration(i) * time.Minute)
+	}
+
+	for i := 0; i < entryCount; i++ {
+		err = collection.Append(seriesId, timestamp(i), float64(i))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	validate := func(op string, t0 time.Time, point DataPoint, err error, expected int) {
+		if err != nil {
+			t.Fatalf("%s(%s) failed: %s", op, t0.Format(layout), err.Error())
+		}
+
+		if expected < 0 {
+			if point != nil {
+				t.Errorf("%s(%s) expected no data point, got %s", op, t0.Format(layout), point.Timestamp().Format(layout))
+			}
+			return
+		}
+
+		if point == nil {
+			t.Errorf("%s(%s) expected data point %d, got nil", op, t0.Format(layout), expected)
+			return
+		}
+
+		v, err := point.Float64()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if !point.Timestamp().Equal(timestamp(expected)) || v != float64(expected) {
+			t.Errorf("%s(%s) expected data point %d, got %v at %s", op, t0.Format(layout), expected, v, point.Timestamp().Format(layout))
+		}
+	}
+
+	point, err := collection.First(seriesId)
+	validate("First", startTime, point, err, 0)
+
+	last := entryCount - 1
+	for i := 0; i < entryCount; i++ {
+		point, err = collection.Before(seriesId, timestamp(i))
+		validate("Before", timestamp(i), point, err, i)
+
+		point, err = collection.Before(seriesId, timestamp(i).Add(30*time.Second))
+		validate("Before", timestamp(i).Add(30*time.Second), point, err, i)
+
+		point, err = collection.After(seriesId, timestamp(i))
+		validate("After", timestamp(i), point, err, i)
+
+		point, err = collection.After(seriesId, timestamp(i).Add(-30*time.Second))
+		validate("After", timestamp(i).Add(-30*time.Second), point, err, i)
+
+		point, err = collection.Nearest(seriesId, timestamp(i).Add(20*time.Second))
+		validate("Nearest", timestamp(i).Add(20*time.Second), point, err, i)
+
+		expected := i + 1
+		if i == last {
+			expected = i
+		}
+		point, err = collection.Nearest(seriesId, timestamp(i).Add(40*time.Second))
+		validate("Nearest", timestamp(i).Add(40*time.Second), point, err, expected)
+	}
+
+	// out of range
+	point, err = collection.Before(seriesId, startTime.Add(-time.Second))
+	validate("Before", startTime.Add(-time.Second), point, err, -1)
+
+	point, err = collection.After(seriesId, timestamp(last).Add(time.Second))
+	validate("After", timestamp(last).Add(time.Second), point, err, -1)
+
+	// missing series
+	_, err = collection.Before(bson.NewObjectId(), startTime)
+	if !errors.Is(err, ErrSeriesNotFound) {
+		t.Errorf("Expected ErrSeriesNotFound for a missing series, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"time"
+)
+
+// Errors
+var ErrInvalidMaxPoints = errors.New("Maximum number of points must be at least 3")
+
+// RangeMaxPoints returns at most n data points between minTime and maxTime,
+// downsampled with the Largest-Triangle-Three-Buckets algorithm so the visual
+// shape of the series is preserved when charted.
+//
+// The range is divided into n-2 buckets of equal duration, with the first and
+// last data points always retained. Null entries are skipped. Pages are
+// streamed from MongoDB one at a time and at most two buckets of values are
+// held in memory.
+func (c *NonperiodicCollection) RangeMaxPoints(seriesId interface{}, minTime time.Time, maxTime time.Time, n int) (DataPoints, error) {
+	if n < 3 {
+		return nil, wrapError("RangeMaxPoints", seriesId, ErrInvalidMaxPoints)
+	}
+
+	iter := c.DBCollection.Find(bson.M{
+		"seriesid":  seriesId,
+		"starttime": bson.M{"$lte": maxTime},
+		"endtime":   bson.M{"$gte": minTime},
+	}).Select(bson.M{"padding": 0}).Sort("starttime").Iter()
+
+	downsampler := newLTTB(minTime, maxTime, n)
+	var page dataPage
+	for iter.Next(&page) {
+		timestamps, values, err := pagesFloat64([]dataPage{page})
+		if err != nil {
+			iter.Close()
+			return nil, wrapError("RangeMaxPoints", seriesId, err)
+		}
+
+		for i, timestamp := range timestamps {
+			if !timestamp.Before(minTime) && !timestamp.After(maxTime) && !math.IsNaN(values[i]) {
+				downsampler.add(sample{timestamp, values[i]})
+			}
+		}
+
+		page = dataPage{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, wrapError("RangeMaxPoints", seriesId, newError(err, "Error searching for time series pages"))
+	}
+
+	return downsampler.finish(), nil
+}
+
+// lttb is a streaming implementation of Largest-Triangle-Three-Buckets
+// downsampling over buckets of equal duration.
+type lttb struct {
+	minTime time.Time
+	width   time.Duration
+	buckets int
+
+	results  DataPoints
+	selected *sample  // most recently selected sample
+	last     *sample  // most recently added sample, held back until the next
+	pending  []sample // bucket awaiting the average of the next bucket
+	current  []sample // bucket currently receiving samples
+	index    int      // index of the current bucket
+}
+
+func newLTTB(minTime time.Time, maxTime time.Time, n int) *lttb {
+	width := maxTime.Sub(minTime) / time.Duration(n-2)
+	if width <= 0 {
+		width = 1
+	}
+
+	return &lttb{
+		minTime: minTime,
+		width:   width,
+		buckets: n - 2,
+		results: make(DataPoints, 0, n),
+	}
+}
+
+func (c *lttb) add(s sample) {
+	// the first sample is always retained
+	if c.selected == nil {
+		c.selected = &s
+		c.results = append(c.results, newFloat64DataPoint(s.timestamp, s.value))
+		return
+	}
+
+	// hold back the most recent sample, as it may be the last
+	if c.last == nil {
+		c.last = &s
+		return
+	}
+
+	s, *c.last = *c.last, s
+
+	index := int(s.timestamp.Sub(c.minTime) / c.width)
+	if index >= c.buckets {
+		index = c.buckets - 1
+	}
+
+	if index != c.index && len(c.current) > 0 {
+		c.selectFrom(c.pending, average(c.current))
+		c.pending = c.current
+		c.current = nil
+	}
+
+	c.index = index
+	c.current = append(c.current, s)
+}
+
+func (c *lttb) finish() DataPoints {
+	if c.last == nil {
+		return c.results
+	}
+
+	if len(c.current) > 0 {
+		c.selectFrom(c.pending, average(c.current))
+		c.selectFrom(c.current, *c.last)
+	} else {
+		c.selectFrom(c.pending, *c.last)
+	}
+
+	// the last sample is always retained
+	c.results = append(c.results, newFloat64DataPoint(c.last.timestamp, c.last.value))
+	return c.results
+}
+
+// selectFrom retains the sample in the given bucket which forms the largest
+// triangle with the previously selected sample and the given sample from the
+// next bucket.
+func (c *lttb) selectFrom(bucket []sample, next sample) {
+	if len(bucket) == 0 {
+		return
+	}
+
+	// timestamps are measured in seconds since the previously selected sample
+	a := c.selected
+	nx := next.timestamp.Sub(a.timestamp).Seconds()
+	maxArea := -1.0
+	var best sample
+	for _, s := range bucket {
+		sx := s.timestamp.Sub(a.timestamp).Seconds()
+		area := math.Abs(nx*(s.value-a.value) - sx*(next.value-a.value))
+		if area > maxArea {
+			maxArea = area
+			best = s
+		}
+	}
+
+	c.selected = &best
+	c.results = append(c.results, newFloat64DataPoint(best.timestamp, best.value))
+}
+
+// average returns a sample at the mean timestamp and value of the given
+// samples.
+func average(samples []sample) sample {
+	origin := samples[0].timestamp
+	var t time.Duration
+	var v float64
+	for _, s := range samples {
+		t += s.timestamp.Sub(origin)
+		v += s.value
+	}
+
+	n := len(samples)
+	return sample{origin.Add(t / time.Duration(n)), v / float64(n)}
+}
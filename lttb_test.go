@@ -0,0 +1,124 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRangeMaxPoints(t *testing.T) {
+	database := DBConnect()
+	name := "test_range_max_points"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Create a sine wave with a single spike, one value per second
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	entryCount := 5000
+	for i := 0; i < entryCount; i++ {
+		value := math.Sin(float64(i) / 100)
+		if i == 2500 {
+			value = 10
+		}
+
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Second), value)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	endTime := startTime.Add(time.Duration(entryCount-1) * time.Second)
+	data, err := collection.RangeMaxPoints(seriesId, startTime, endTime, 100)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(data) != 100 {
+		t.Fatalf("Expected 100 data points, got %d", len(data))
+	}
+
+	// first and last points should be retained
+	if !data[0].Timestamp().Equal(startTime) || !data[99].Timestamp().Equal(endTime) {
+		t.Errorf("Expected first and last data points to be retained, got %s and %s", data[0].Timestamp().Format(layout), data[99].Timestamp().Format(layout))
+	}
+
+	// the spike should be retained
+	spike := false
+	for i, point := range data {
+		if v, _ := point.Float64(); v == 10 {
+			spike = true
+		}
+
+		if i > 0 && !point.Timestamp().After(data[i-1].Timestamp()) {
+			t.Errorf("Data point %d is not more recent than data point %d", i, i-1)
+		}
+	}
+
+	if !spike {
+		t.Errorf("Expected downsampled data to retain the spike")
+	}
+}
+
+func TestRangeMaxPointsNull(t *testing.T) {
+	database := DBConnect()
+	name := "test_range_max_points_null"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Every third entry is null, including the first and last
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	entryCount := 100
+	for i := 0; i < entryCount; i++ {
+		var value interface{} = float64(i)
+		if i%3 == 0 {
+			value = nil
+		}
+
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Second), value)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	endTime := startTime.Add(time.Duration(entryCount-1) * time.Second)
+	data, err := collection.RangeMaxPoints(seriesId, startTime, endTime, 10)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(data) != 10 {
+		t.Fatalf("Expected 10 data points, got %d", len(data))
+	}
+
+	for i, point := range data {
+		v, err := point.Float64()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if math.IsNaN(v) || point.Timestamp().Before(startTime) || int(v)%3 == 0 {
+			t.Errorf("Expected data point %d to be a non-null entry, got %v at %s", i, v, point.Timestamp().Format(layout))
+		}
+	}
+}
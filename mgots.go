@@ -12,7 +12,11 @@ type dataPage struct {
 	EndTime    time.Time   // Timestamp of the first entry in the next page
 	Timestamps []time.Time `bson:",omitempty"` // Array of timestamps for all entries in the page
 	Values     []bson.Raw  `bson:",omitempty"` // time series values for this page
+	Float64s   []float64   `bson:",omitempty"` // packed time series values for pages of float64 series
 	Padding    []byte      `bson:",omitempty"` // padding data to set initial page size
+	Sketch     *Sketch     `bson:",omitempty"` // quantile sketch of numeric values in this page
+	Summary    *summary    `bson:",omitempty"` // summary statistics of numeric values in this page
+	Mixed      bool        `bson:",omitempty"` // true if the page contains values which are not summarized
 }
 
 const (
@@ -21,11 +25,48 @@ const (
 )
 
 type seriesCursor struct {
-	SeriesId      interface{} `bson:"_id"` // ID of the series described by this cursor
-	LastPage      interface{} // StartTime (ID) of the page pointed to by this cursor
-	NextSlotId    int         // Index of the next available slot in the page pointed to by this cursor
-	LastValueTime time.Time   `bson:",omitempty"` // Timestamp of the last entry in the series described by this cursor
-	LastValue     bson.Raw    `bson:",omitempty"` // Value of the last entry in the series described by this cursor
+	SeriesId      interface{}       `bson:"_id"`        // ID of the series described by this cursor
+	StartTime     time.Time         `bson:",omitempty"` // Timestamp before which no entries may be appended to the series
+	LastPage      interface{}       // StartTime (ID) of the page pointed to by this cursor
+	NextSlotId    int               // Index of the next available slot in the page pointed to by this cursor
+	LastValueTime time.Time         `bson:",omitempty"` // Timestamp of the last entry in the series described by this cursor
+	LastValue     bson.Raw          `bson:",omitempty"` // Value of the last entry in the series described by this cursor
+	Float64       bool              `bson:",omitempty"` // True if values are stored in pages as packed float64 arrays
+	Summarized    bool              `bson:",omitempty"` // True if the page pointed to by this cursor maintains a summary
+	Stats         *seriesStats      `bson:",omitempty"` // Lifetime statistics of the series described by this cursor
+	Tags          map[string]string `bson:",omitempty"` // Name/value pairs describing the series
+	Writes        []writeRecord     `bson:",omitempty"` // Most recent writes applied to the series described by this cursor
+}
+
+// entries returns the number of used slots in the page.
+func (c *dataPage) entries() int {
+	n := 0
+	for _, timestamp := range c.Timestamps {
+		if !timestamp.IsZero() {
+			n++
+		}
+	}
+
+	return n
+}
+
+// valuesField returns the name of the page field in which the values of the
+// series described by the cursor are stored.
+func (c *seriesCursor) valuesField() string {
+	if c.Float64 {
+		return "float64s"
+	}
+
+	return "values"
+}
+
+// SeriesInfo describes a time series.
+type SeriesInfo struct {
+	SeriesId      interface{}
+	StartTime     time.Time         // Timestamp before which no entries may be appended to the series
+	LastValueTime time.Time         // Timestamp of the most recent entry or the zero time if the series is empty
+	Float64       bool              // True if the series was created with CreateFloat64Series
+	Tags          map[string]string // Name/value pairs describing the series
 }
 
 var cursorSuffix = "_cursors"
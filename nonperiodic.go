@@ -2,8 +2,11 @@ package mgots
 
 import (
 	"errors"
+	"fmt"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"math"
+	"sort"
 	"strconv"
 	"time"
 )
@@ -11,6 +14,34 @@ import (
 type NonperiodicCollection struct {
 	collection
 	PageSize int
+
+	// Sketches enables the maintenance of a quantile sketch of the numeric
+	// values in each page, so SketchQuantile can summarize long ranges
+	// without decoding every value.
+	Sketches bool
+
+	// Rollups are the intervals of the rollup tiers maintained for each
+	// series (E.g. time.Minute, time.Hour). The count, sum, minimum,
+	// maximum, first and last numeric value of each interval are stored in a
+	// companion collection for each tier as values are appended, and
+	// Aggregate uses the coarsest tier which satisfies the requested interval.
+	// Each tier must be a whole number of seconds. Rollups only summarize
+	// intervals, so Range and other queries of raw values do not use them.
+	Rollups []time.Duration
+
+	// AutoCreate enables the creation of a series by the first entry
+	// appended to it, rather than returning ErrSeriesNotFound.
+	AutoCreate bool
+
+	// Duplicates determines how entries with the same timestamp as the
+	// most recent entry of a series are appended.
+	Duplicates DuplicatePolicy
+}
+
+// AppendOptions controls how a value is appended by AppendWithOptions.
+type AppendOptions struct {
+	AutoCreate bool   // create the series if it does not exist, as per NonperiodicCollection.AutoCreate
+	WriteId    string // identifies the write so retries of a write which was already applied have no effect
 }
 
 // Errors
@@ -18,13 +49,13 @@ var ErrSeriesNotFound = errors.New("Series not found with the specified collecti
 var ErrInvalidPageSize = errors.New("Pages size must be greater than 256 bytes")
 var ErrDuplicateSeries = errors.New("Time series already exists with the specified ID")
 var ErrValueTooLarge = errors.New("The size value specified exceeds the maximum Time Series page size.")
-var ErrTooOld = errors.New("The timestamp of the specified value is older than the most recent entry or the series does not exist.")
+var ErrTooOld = errors.New("The timestamp of the specified value is older than the most recent entry.")
 var ErrNoData = errors.New("No existing data to update")
 
-func NewNonperiodicCollection(database *mgo.Database, name string, pageSize int) (Collection, error) {
+func NewNonperiodicCollection(database *mgo.Database, name string, pageSize int) (*NonperiodicCollection, error) {
 	// Validate page size
 	if pageSize < 256 {
-		return nil, ErrInvalidPageSize
+		return nil, wrapError("NewNonperiodicCollection", nil, ErrInvalidPageSize)
 	}
 
 	// Build collection struct
@@ -47,6 +78,17 @@ func NewNonperiodicCollection(database *mgo.Database, name string, pageSize int)
 }
 
 func (c *NonperiodicCollection) CreateSeries(seriesId interface{}, startTime time.Time) error {
+	return wrapError("CreateSeries", seriesId, c.createSeries(seriesId, startTime, false))
+}
+
+// CreateFloat64Series creates a series in which all values are stored in
+// pages as packed arrays of float64 values, so they can be read in bulk with
+// RangeFloat64.
+func (c *NonperiodicCollection) CreateFloat64Series(seriesId interface{}, startTime time.Time) error {
+	return wrapError("CreateFloat64Series", seriesId, c.createSeries(seriesId, startTime, true))
+}
+
+func (c *NonperiodicCollection) createSeries(seriesId interface{}, startTime time.Time, float64Series bool) error {
 
 	// Does the series exist?
 	count, err := c.DBCursorCollection.FindId(seriesId).Count()
@@ -61,7 +103,10 @@ func (c *NonperiodicCollection) CreateSeries(seriesId interface{}, startTime tim
 	// Create new time series cursor
 	err = c.DBCursorCollection.Insert(seriesCursor{
 		SeriesId:      seriesId,
+		StartTime:     startTime,
 		LastValueTime: timeZero,
+		Float64:       float64Series,
+		Stats:         &seriesStats{Complete: true},
 	})
 	if err != nil {
 		return newError(err, "Error creating new series")
@@ -70,16 +115,42 @@ func (c *NonperiodicCollection) CreateSeries(seriesId interface{}, startTime tim
 	return nil
 }
 
+// Info returns a description of a series.
+func (c *NonperiodicCollection) Info(seriesId interface{}) (*SeriesInfo, error) {
+	var cursor seriesCursor
+	err := c.DBCursorCollection.FindId(seriesId).Select(bson.M{"lastvalue": 0}).One(&cursor)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, wrapError("Info", seriesId, ErrSeriesNotFound)
+		}
+
+		return nil, wrapError("Info", seriesId, newError(err, "Error searching for time series"))
+	}
+
+	info := &SeriesInfo{
+		SeriesId:  cursor.SeriesId,
+		StartTime: cursor.StartTime,
+		Float64:   cursor.Float64,
+		Tags:      cursor.Tags,
+	}
+
+	if !cursor.LastValueTime.Equal(timeZero) {
+		info.LastValueTime = cursor.LastValueTime
+	}
+
+	return info, nil
+}
+
 func (c *NonperiodicCollection) Latest(seriesId interface{}) (DataPoint, error) {
 	// Fetch last value from the series cursor
 	var cursor seriesCursor
 	err := c.DBCursorCollection.FindId(seriesId).One(&cursor)
 	if err != nil {
 		if err == mgo.ErrNotFound {
-			return nil, ErrSeriesNotFound
+			return nil, wrapError("Latest", seriesId, ErrSeriesNotFound)
 		}
 
-		return nil, newError(err, "Error searching for time series")
+		return nil, wrapError("Latest", seriesId, newError(err, "Error searching for time series"))
 	}
 
 	if cursor.LastValueTime.Equal(timeZero) {
@@ -92,19 +163,31 @@ func (c *NonperiodicCollection) Latest(seriesId interface{}) (DataPoint, error)
 	}, nil
 }
 
-func (c *NonperiodicCollection) Range(seriesId interface{}, minTime time.Time, maxTime time.Time) (DataPoints, error) {
-	// search for matching pages
+// findPages returns all pages of a series which overlap the given time range,
+// in chronological order. Fields of each page may be excluded from the
+// results with the given selector.
+func (c *NonperiodicCollection) findPages(seriesId interface{}, minTime time.Time, maxTime time.Time, selector interface{}) ([]dataPage, error) {
 	var pages []dataPage
 	err := c.DBCollection.Find(bson.M{
 		"seriesid":  seriesId,
 		"starttime": bson.M{"$lte": maxTime},
 		"endtime":   bson.M{"$gte": minTime},
-	}).Sort("starttime").All(&pages)
+	}).Select(selector).Sort("starttime").All(&pages)
 
 	if err != nil {
 		return nil, newError(err, "Error searching for time series pages")
 	}
 
+	return pages, nil
+}
+
+func (c *NonperiodicCollection) Range(seriesId interface{}, minTime time.Time, maxTime time.Time) (DataPoints, error) {
+	// search for matching pages
+	pages, err := c.findPages(seriesId, minTime, maxTime, bson.M{"padding": 0})
+	if err != nil {
+		return nil, wrapError("Range", seriesId, err)
+	}
+
 	// create capacity for result set
 	// estimate len(pages[0].Timestamps)
 	pagesLen := len(pages)
@@ -118,16 +201,11 @@ func (c *NonperiodicCollection) Range(seriesId interface{}, minTime time.Time, m
 	j := 0
 	results := make(DataPoints, resultsLen)
 	for _, page := range pages {
-		values := page.Values
-
 		for i := len(page.Timestamps) - 1; i >= 0; i-- {
 			timestamp := page.Timestamps[i]
 
 			if (timestamp.Equal(minTime) || timestamp.After(minTime)) && (timestamp.Equal(maxTime) || timestamp.Before(maxTime)) {
-				results[j] = &dataPoint{
-					timestamp,
-					values[i],
-				}
+				results[j] = page.point(i)
 				j++
 			}
 		}
@@ -137,41 +215,259 @@ func (c *NonperiodicCollection) Range(seriesId interface{}, minTime time.Time, m
 	return results[0:j], nil
 }
 
+// RangeFloat64 returns the timestamps and values of all data points in a
+// numeric series between minTime and maxTime. Values are read directly from
+// the packed arrays of series created with CreateFloat64Series, and decoded
+// from numeric BSON values for all other series.
+func (c *NonperiodicCollection) RangeFloat64(seriesId interface{}, minTime time.Time, maxTime time.Time) ([]time.Time, []float64, error) {
+	// search for matching pages
+	pages, err := c.findPages(seriesId, minTime, maxTime, bson.M{"padding": 0})
+	if err != nil {
+		return nil, nil, wrapError("RangeFloat64", seriesId, err)
+	}
+
+	timestamps, values, err := pagesFloat64(pages)
+	if err != nil {
+		return nil, nil, wrapError("RangeFloat64", seriesId, err)
+	}
+
+	// trim entries outside of the range from the boundary pages
+	first := sort.Search(len(timestamps), func(i int) bool {
+		return !timestamps[i].Before(minTime)
+	})
+	last := sort.Search(len(timestamps), func(i int) bool {
+		return timestamps[i].After(maxTime)
+	})
+	if last < first {
+		last = first
+	}
+
+	return timestamps[first:last], values[first:last], nil
+}
+
+// pagesFloat64 returns the timestamps and numeric values of every entry in
+// the given pages, in chronological order.
+func pagesFloat64(pages []dataPage) ([]time.Time, []float64, error) {
+	// create capacity for result set
+	capacity := 0
+	for _, page := range pages {
+		capacity += len(page.Timestamps)
+	}
+
+	timestamps := make([]time.Time, 0, capacity)
+	values := make([]float64, 0, capacity)
+	for _, page := range pages {
+		for i := len(page.Timestamps) - 1; i >= 0; i-- {
+			timestamp := page.Timestamps[i]
+
+			// skip unused slots
+			if timestamp.IsZero() {
+				continue
+			}
+
+			if page.Float64s != nil {
+				values = append(values, page.Float64s[i])
+			} else {
+				v, err := rawFloat64(page.Values[i])
+				if err != nil {
+					return nil, nil, err
+				}
+				values = append(values, v)
+			}
+			timestamps = append(timestamps, timestamp)
+		}
+	}
+
+	return timestamps, values, nil
+}
+
+// Aggregate returns one data point for each interval between minTime and
+// maxTime, computed from the numeric values which fall within the interval.
+// Intervals are computed from rollups if a suitable tier is configured, or
+// otherwise from page summaries, so only pages which span the boundary of an
+// interval are decoded.
+func (c *NonperiodicCollection) Aggregate(seriesId interface{}, minTime time.Time, maxTime time.Time, interval time.Duration, aggregator Aggregator) (DataPoints, error) {
+	if interval <= 0 {
+		return nil, wrapError("Aggregate", seriesId, ErrInvalidInterval)
+	}
+
+	if tier := c.rollupTier(interval); tier > 0 {
+		if err := c.prepareRollups(); err != nil {
+			return nil, wrapError("Aggregate", seriesId, err)
+		}
+
+		points, err := c.aggregateRollups(seriesId, minTime, maxTime, interval, aggregator, tier)
+		if err != nil {
+			return nil, wrapError("Aggregate", seriesId, err)
+		}
+
+		return points, nil
+	}
+
+	points, err := c.aggregatePages(seriesId, minTime, maxTime, fixedBuckets(interval), aggregator)
+	if err != nil {
+		return nil, wrapError("Aggregate", seriesId, err)
+	}
+
+	return points, nil
+}
+
+// ListSeries returns the ID of every series in the collection.
+func (c *NonperiodicCollection) ListSeries() ([]interface{}, error) {
+	var cursors []seriesCursor
+	err := c.DBCursorCollection.Find(nil).Select(bson.M{"_id": 1}).Sort("_id").All(&cursors)
+	if err != nil {
+		return nil, wrapError("ListSeries", nil, newError(err, "Error listing series cursors"))
+	}
+
+	series := make([]interface{}, len(cursors))
+	for i, cursor := range cursors {
+		series[i] = cursor.SeriesId
+	}
+
+	return series, nil
+}
+
 /*
  * value must have a consistent size
  */
 func (c *NonperiodicCollection) Append(seriesId interface{}, timestamp time.Time, value interface{}) error {
+	return c.appendValue("Append", seriesId, timestamp, value, AppendOptions{}, 0)
+}
+
+// AppendWithOptions appends a value to a series as per Append, with the
+// given options.
+func (c *NonperiodicCollection) AppendWithOptions(seriesId interface{}, timestamp time.Time, value interface{}, options AppendOptions) error {
+	return c.appendValue("AppendWithOptions", seriesId, timestamp, value, options, 0)
+}
+
+// appendValue appends an entry to a series. The entry is identified by its
+// index within the write described by options.
+func (c *NonperiodicCollection) appendValue(op string, seriesId interface{}, timestamp time.Time, value interface{}, options AppendOptions, entry int) error {
+	if len(c.Rollups) > 0 {
+		if err := c.prepareRollups(); err != nil {
+			return wrapError(op, seriesId, err)
+		}
+	}
+
 	// Query to find the series cursor
-	query := c.DBCursorCollection.Find(bson.M{
+	// Timestamps may not precede the start time of the series
+	// Non-numeric values may not be appended to float64 series
+	selector := bson.M{
 		"_id":           seriesId,
 		"lastvaluetime": bson.M{"$lt": timestamp},
-	})
+		"$or": []bson.M{
+			{"starttime": bson.M{"$lte": timestamp}},
+			{"starttime": bson.M{"$exists": false}},
+		},
+	}
+
+	if c.Duplicates == DuplicateKeep {
+		selector["lastvaluetime"] = bson.M{"$lte": timestamp}
+	}
+
+	f, numeric := toFloat64(value)
+	if !numeric {
+		selector["float64"] = bson.M{"$ne": true}
+	}
 
 	// Compile the change to apply to the cursor
-	change := mgo.Change{
-		Update: bson.M{
-			"$set": bson.M{
-				"lastvalue":     value,
-				"lastvaluetime": timestamp,
-			},
-			"$inc": bson.M{
-				"nextslotid": -1,
-			},
+	update := bson.M{
+		"$set": bson.M{
+			"lastvalue":     value,
+			"lastvaluetime": timestamp,
 		},
+		"$inc": bson.M{
+			"nextslotid": -1,
+		},
+	}
+	statsChange(update, timestamp, f, numeric)
+
+	// Record the entry as applied by the write
+	if options.WriteId != "" {
+		writeChange(selector, update, options.WriteId, entry)
+	}
+
+	query := c.DBCursorCollection.Find(selector)
+	change := mgo.Change{
+		Update:    update,
 		ReturnNew: true,
 	}
 
+	// Create the series cursor if it does not exist. The series starts with
+	// this entry.
+	if (c.AutoCreate || options.AutoCreate) && entry == 0 {
+		update["$setOnInsert"] = bson.M{
+			"starttime":      timestamp,
+			"stats.complete": true,
+		}
+		change.Upsert = true
+	}
+
 	// Search and update the series cursor
 	var cursor seriesCursor
 	_, err := query.Apply(change, &cursor)
+	if err != nil && mgo.IsDup(err) {
+		// the series exists but did not match, or was created concurrently
+		change.Upsert = false
+		_, err = query.Apply(change, &cursor)
+	}
+
 	if err != nil {
 		if err == mgo.ErrNotFound {
-			return ErrTooOld
+			err = c.appendError(&cursor, seriesId, timestamp, numeric, options.WriteId, entry)
+			if err == errWriteApplied {
+				return nil
+			}
+
+			// write the entry to the slot reserved by a previous attempt
+			if err == errWriteIncomplete {
+				return c.writeEntry(op, seriesId, &cursor, timestamp, value)
+			}
+
+			if err == ErrDuplicateTimestamp && (c.Duplicates == DuplicateOverwrite || c.Duplicates == DuplicateIgnore) {
+				if c.Duplicates == DuplicateOverwrite {
+					if err := c.updateValue(op, seriesId, value); err != nil {
+						return err
+					}
+				}
+				return wrapError(op, seriesId, c.recordWrite(seriesId, options.WriteId, entry))
+			}
+
+			return wrapError(op, seriesId, err)
+		}
+		return wrapError(op, seriesId, newError(err, "Error updating series cursor"))
+	}
+
+	return c.writeEntry(op, seriesId, &cursor, timestamp, value)
+}
+
+// writeEntry writes an entry to the slot reserved for it on the series cursor
+// and updates the rollups of the series.
+func (c *NonperiodicCollection) writeEntry(op string, seriesId interface{}, cursor *seriesCursor, timestamp time.Time, value interface{}) error {
+	var err error
+	f, numeric := toFloat64(value)
+
+	// Store packed values as float64. The cursor was updated before the
+	// type of the series was known, so its last value is replaced unless a
+	// later entry has already been appended.
+	if cursor.Float64 {
+		if _, ok := value.(float64); !ok {
+			err = c.DBCursorCollection.Update(bson.M{
+				"_id":           seriesId,
+				"lastvaluetime": timestamp,
+			}, bson.M{
+				"$set": bson.M{"lastvalue": f},
+			})
+			if err != nil && err != mgo.ErrNotFound {
+				return wrapError(op, seriesId, newError(err, "Error updating series cursor"))
+			}
 		}
-		return newError(err, "Error updating series cursor")
+		value = f
 	}
 
 	// Create a new page if the NextSlotId is < 0
+	newPageCreated := false
 	if cursor.NextSlotId < 0 {
 		// Fetch and update last page
 		var lastPage dataPage
@@ -189,11 +485,14 @@ func (c *NonperiodicCollection) Append(seriesId interface{}, timestamp time.Time
 
 		if err != nil {
 			if err != mgo.ErrNotFound {
-				return newError(err, "Error updating most recent series page")
+				return wrapError(op, seriesId, newError(err, "Error updating most recent series page"))
 			}
 
-			// No previous page
+			// No previous page, so the first page starts with the series
 			lastPage.EndTime = timestamp
+			if !cursor.StartTime.IsZero() {
+				lastPage.EndTime = cursor.StartTime
+			}
 		}
 
 		// Create new page
@@ -208,14 +507,20 @@ func (c *NonperiodicCollection) Append(seriesId interface{}, timestamp time.Time
 		bsonSize := BSONSize(value) + TIMESTAMP_SIZE
 		slots := int((c.PageSize - PAGE_HEADER_SIZE) / bsonSize)
 		if slots < 1 {
-			return ErrValueTooLarge
+			return wrapError(op, seriesId, ErrValueTooLarge)
 		}
 
 		// Preallocate null data into page slots
+		// Packed float64 values have a fixed size and are preallocated in full
 		newPage.Timestamps = make([]time.Time, slots)
-		newPage.Values = []bson.Raw{bsonZero}
-
 		paddingSize := c.PageSize - PAGE_HEADER_SIZE - (slots * TIMESTAMP_SIZE) - 16
+		if cursor.Float64 {
+			newPage.Float64s = make([]float64, slots)
+			paddingSize -= slots * (bsonSize - TIMESTAMP_SIZE)
+		} else {
+			newPage.Values = []bson.Raw{bsonZero}
+		}
+
 		if paddingSize > 0 {
 			newPage.Padding = make([]byte, paddingSize)
 		}
@@ -223,7 +528,7 @@ func (c *NonperiodicCollection) Append(seriesId interface{}, timestamp time.Time
 		// Insert new page
 		err = c.DBCollection.Insert(newPage)
 		if err != nil {
-			return newError(err, "Error inserting new page")
+			return wrapError(op, seriesId, newError(err, "Error inserting new page"))
 		}
 
 		// Update cursor in database
@@ -231,38 +536,123 @@ func (c *NonperiodicCollection) Append(seriesId interface{}, timestamp time.Time
 			"$set": bson.M{
 				"lastpage":   newPage.PageId,
 				"nextslotid": slots, // -1 for the entry we already added
+				"summarized": true,
 			},
 		})
 		if err != nil {
-			return newError(err, "Error updating series cursor with latest page")
+			return wrapError(op, seriesId, newError(err, "Error updating series cursor with latest page"))
 		}
 
 		// update cursor for next operation
 		cursor.LastPage = newPage.PageId
 		cursor.NextSlotId = slots
+		cursor.Summarized = true
+		newPageCreated = true
 	}
 
 	// Update the next page and slot with this data
 	slotIdString := strconv.FormatInt(int64(cursor.NextSlotId), 10)
-	err = c.DBCollection.UpdateId(cursor.LastPage, bson.M{
+	pageChange := bson.M{
 		"$set": bson.M{
 			"endtime":                    timestamp,
 			"timestamps." + slotIdString: timestamp,
-			"values." + slotIdString:     value,
+			cursor.valuesField() + "." + slotIdString: value,
 		},
 		"$unset": bson.M{
 			"padding": "",
 		},
-	})
+	}
+
+	// Update the page summary, unless the page was created before summaries
+	// were introduced and would be incomplete
+	inc := bson.M{}
+	if numeric && !math.IsNaN(f) && cursor.Summarized {
+		inc["summary.count"] = 1
+		inc["summary.sum"] = f
+		pageChange["$min"] = bson.M{"summary.min": f}
+		pageChange["$max"] = bson.M{"summary.max": f}
+		pageChange["$set"].(bson.M)["summary.last"] = f
+		if newPageCreated {
+			pageChange["$set"].(bson.M)["summary.first"] = f
+		}
+	} else if cursor.Summarized {
+		pageChange["$set"].(bson.M)["mixed"] = true
+	}
 
+	if c.Sketches && numeric && !math.IsNaN(f) {
+		for k, n := range sketchInc("sketch", f, 1) {
+			inc[k] = n
+		}
+	}
+
+	if len(inc) > 0 {
+		pageChange["$inc"] = inc
+	}
+
+	err = c.DBCollection.UpdateId(cursor.LastPage, pageChange)
 	if err != nil {
-		return newError(err, "Error updating page with most recent data")
+		return wrapError(op, seriesId, newError(err, "Error updating page with most recent data"))
+	}
+
+	// Update rollup tiers
+	if numeric && !math.IsNaN(f) {
+		if err := c.appendRollups(seriesId, timestamp, f); err != nil {
+			return wrapError(op, seriesId, err)
+		}
 	}
 
 	return nil
 }
 
+// appendError reads the series cursor and returns the reason that an entry
+// could not be appended to the series, errWriteApplied if it was appended by a
+// previous attempt of the same write, or errWriteIncomplete if a previous
+// attempt reserved its slot but did not write it.
+func (c *NonperiodicCollection) appendError(cursor *seriesCursor, seriesId interface{}, timestamp time.Time, numeric bool, writeId string, entry int) error {
+	err := c.DBCursorCollection.FindId(seriesId).Select(bson.M{"lastvalue": 0}).One(cursor)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return ErrSeriesNotFound
+		}
+		return newError(err, "Error searching for series cursor")
+	}
+
+	if writeId != "" {
+		err := cursor.checkWrite(writeId, entry)
+		if err == errWriteApplied {
+			err = c.checkWritten(cursor, timestamp)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if cursor.Float64 && !numeric {
+		return ErrNotNumeric
+	}
+
+	// timestamps are stored with millisecond precision
+	if cursor.LastValueTime.Equal(timestamp.Truncate(time.Millisecond)) {
+		return ErrDuplicateTimestamp
+	}
+
+	return ErrTooOld
+}
+
+// Update replaces the value of the most recent entry of a series. If the
+// replaced value was the minimum or maximum of the series, Stats returns
+// ErrStatsUnavailable until RecomputeStats is called.
 func (c *NonperiodicCollection) Update(seriesId interface{}, value interface{}) error {
+	return c.updateValue("Update", seriesId, value)
+}
+
+func (c *NonperiodicCollection) updateValue(op string, seriesId interface{}, value interface{}) error {
+	if len(c.Rollups) > 0 {
+		if err := c.prepareRollups(); err != nil {
+			return wrapError(op, seriesId, err)
+		}
+	}
+
 	// Search for the series cursor
 	var cursor seriesCursor
 	err := c.DBCursorCollection.Find(bson.M{
@@ -271,14 +661,23 @@ func (c *NonperiodicCollection) Update(seriesId interface{}, value interface{})
 
 	if err != nil {
 		if err == mgo.ErrNotFound {
-			return ErrSeriesNotFound
+			return wrapError(op, seriesId, ErrSeriesNotFound)
 		}
-		return newError(err, "Error searching for series cursor")
+		return wrapError(op, seriesId, newError(err, "Error searching for series cursor"))
 	}
 
 	// Fail if no value exists
 	if cursor.LastValueTime.Equal(timeZero) {
-		return ErrNoData
+		return wrapError(op, seriesId, ErrNoData)
+	}
+
+	// Only numeric values may be stored in float64 series
+	f, numeric := toFloat64(value)
+	if cursor.Float64 {
+		if !numeric {
+			return wrapError(op, seriesId, ErrNotNumeric)
+		}
+		value = f
 	}
 
 	// Update cursor
@@ -287,21 +686,102 @@ func (c *NonperiodicCollection) Update(seriesId interface{}, value interface{})
 			"lastvalue": value,
 		},
 	}
+
+	// Replace the previous value in the series statistics. If the previous
+	// value was the minimum or maximum, the statistics are marked incomplete
+	// until RecomputeStats is called.
+	if cursor.Stats != nil {
+		old, err := rawFloat64(cursor.LastValue)
+		oldNumeric := err == nil && !math.IsNaN(old)
+		newNumeric := numeric && !math.IsNaN(f)
+
+		numericDelta, sumDelta := 0, 0.0
+		if oldNumeric {
+			numericDelta--
+			sumDelta -= old
+			if (old == cursor.Stats.Min || old == cursor.Stats.Max) && !(newNumeric && f == old) {
+				change["$set"].(bson.M)["stats.complete"] = false
+			}
+		}
+		if newNumeric {
+			numericDelta++
+			sumDelta += f
+			change["$min"] = bson.M{"stats.min": f}
+			change["$max"] = bson.M{"stats.max": f}
+		}
+
+		change["$inc"] = bson.M{
+			"stats.numeric": numericDelta,
+			"stats.sum":     sumDelta,
+		}
+	}
+
 	err = c.DBCursorCollection.UpdateId(cursor.SeriesId, change)
 	if err != nil {
-		return newError(err, "Error updating value on series cursor")
+		return wrapError(op, seriesId, newError(err, "Error updating value on series cursor"))
 	}
 
 	// update last page
-	slot := strconv.FormatInt(int64(cursor.NextSlotId+1), 10)
+	slot := strconv.FormatInt(int64(cursor.NextSlotId), 10)
 	change = bson.M{
 		"$set": bson.M{
-			"values." + slot: value,
+			cursor.valuesField() + "." + slot: value,
 		},
 	}
+
 	err = c.DBCollection.UpdateId(cursor.LastPage, change)
 	if err != nil {
-		return newError(err, "Error updating most recent page")
+		return wrapError(op, seriesId, newError(err, "Error updating most recent page"))
+	}
+
+	// replace the previous value in the page sketch, unless the page was
+	// created before sketches were enabled
+	if c.Sketches {
+		inc := bson.M{}
+		if old, err := rawFloat64(cursor.LastValue); err == nil && !math.IsNaN(old) {
+			inc = sketchInc("sketch", old, -1)
+		}
+		if numeric && !math.IsNaN(f) {
+			for k, n := range sketchInc("sketch", f, 1) {
+				if m, ok := inc[k]; ok {
+					n = m.(int64) + n.(int64)
+				}
+				inc[k] = n
+			}
+		}
+		if len(inc) > 0 {
+			err = c.DBCollection.Update(bson.M{
+				"_id":    cursor.LastPage,
+				"sketch": bson.M{"$exists": true},
+			}, bson.M{"$inc": inc})
+			if err != nil && err != mgo.ErrNotFound {
+				return wrapError(op, seriesId, newError(err, "Error updating most recent page sketch"))
+			}
+		}
+	}
+
+	// Recompute the page summary
+	if cursor.Summarized {
+		if err := c.summarizePage(cursor.LastPage); err != nil {
+			return wrapError(op, seriesId, err)
+		}
+	}
+
+	// Replace the updated value in the rollups which contain it
+	if len(c.Rollups) > 0 {
+		old, err := rawFloat64(cursor.LastValue)
+		if err != nil {
+			old = math.NaN()
+		}
+
+		v := f
+		if !numeric {
+			v = math.NaN()
+		}
+
+		if err := c.updateRollups(seriesId, cursor.LastValueTime, old, v); err != nil {
+			return wrapError(op, seriesId, newError(fmt.Errorf("%w: %w", ErrRollupsNotRebuilt, err), "Error updating rollups"))
+		}
 	}
 
 	return nil
@@ -1,6 +1,7 @@
 package mgots
 
 import (
+	"errors"
 	"gopkg.in/mgo.v2/bson"
 	"testing"
 	"time"
@@ -47,7 +48,7 @@ func TestNPOldData(t *testing.T) {
 	for i := 1; i < 10; i++ {
 		timestamp = timestamp.Add(time.Duration(0-i) * time.Microsecond)
 		err = collection.Append(seriesId, timestamp, value)
-		if err != ErrTooOld {
+		if !errors.Is(err, ErrTooOld) {
 			t.Errorf("Timestamp was too old but did not cause an error")
 		}
 	}
@@ -345,3 +346,315 @@ func TestNPRanging(t *testing.T) {
 		}
 	}
 }
+
+func TestNPAggregate(t *testing.T) {
+	database := DBConnect()
+	name := "test_np_aggregate"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Create a new series in the collection
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Add one value for every minute of the day
+	endTime := startTime.AddDate(0, 0, 1)
+	for timestamp, i := startTime, 0; timestamp.Before(endTime); timestamp, i = timestamp.Add(time.Minute), i+1 {
+		err = collection.Append(seriesId, timestamp, float64(i%60))
+		if err != nil {
+			t.Errorf(err.Error())
+		}
+	}
+
+	// Aggregate each hour
+	tests := map[Aggregator]float64{
+		AggregateAvg:   29.5,
+		AggregateSum:   1770,
+		AggregateMin:   0,
+		AggregateMax:   59,
+		AggregateCount: 60,
+		AggregateFirst: 0,
+		AggregateLast:  59,
+	}
+
+	for aggregator, expected := range tests {
+		data, err := collection.Aggregate(seriesId, startTime, endTime, time.Hour, aggregator)
+		if err != nil {
+			t.Fatalf("Error aggregating with %s: %s", aggregator, err.Error())
+		}
+
+		if len(data) != 24 {
+			t.Errorf("Expected 24 %s aggregates, got %d", aggregator, len(data))
+		}
+
+		for i, point := range data {
+			if !point.Timestamp().Equal(startTime.Add(time.Duration(i) * time.Hour)) {
+				t.Errorf("Unexpected timestamp for %s aggregate %d: %s", aggregator, i, point.Timestamp().Format(layout))
+			}
+
+			v, err := point.Float64()
+			if err != nil {
+				t.Errorf("Error reading %s aggregate %d: %s", aggregator, i, err.Error())
+			} else if v != expected {
+				t.Errorf("Expected %s aggregate %d to be %v, got %v", aggregator, i, expected, v)
+			}
+		}
+	}
+}
+
+func TestNPListSeries(t *testing.T) {
+	database := DBConnect()
+	name := "test_np_list_series"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Create some series
+	startTime := time.Now().AddDate(-1, 0, 0)
+	for i := 0; i < 10; i++ {
+		err = collection.CreateSeries(i, startTime)
+		if err != nil {
+			t.Errorf(err.Error())
+		}
+	}
+
+	series, err := collection.ListSeries()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(series) != 10 {
+		t.Errorf("Expected 10 series, got %d", len(series))
+	}
+
+	for i, seriesId := range series {
+		if seriesId != i {
+			t.Errorf("Expected series %d, got %v", i, seriesId)
+		}
+	}
+}
+
+func TestNPFloat64(t *testing.T) {
+	database := DBConnect()
+	name := "test_np_float64"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Create a new float64 series in the collection
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0)
+	err = collection.CreateFloat64Series(seriesId, startTime)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Add sequencial data across multiple pages
+	entryCount := 1000
+	for i := 0; i < entryCount; i++ {
+		timestamp := startTime.Add(time.Duration(i) * time.Minute)
+		err := collection.Append(seriesId, timestamp, i)
+		if err != nil {
+			t.Errorf(err.Error())
+		}
+	}
+
+	// Non-numeric values should be rejected
+	err = collection.Append(seriesId, time.Now(), "x")
+	if !errors.Is(err, ErrNotNumeric) {
+		t.Errorf("Expected ErrNotNumeric when appending a string, got %v", err)
+	}
+
+	// Validate bulk read
+	timestamps, values, err := collection.RangeFloat64(seriesId, startTime, time.Now())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(timestamps) != entryCount || len(values) != entryCount {
+		t.Fatalf("Expected %d data entries to be returned. Got %d timestamps and %d values.", entryCount, len(timestamps), len(values))
+	}
+
+	for i, v := range values {
+		if v != float64(i) {
+			t.Errorf("Expected value %d to be %d. Got %v.", i, i, v)
+		}
+
+		if i > 0 && timestamps[i].Before(timestamps[i-1]) {
+			t.Errorf("Sequence %d is more recent than sequence %d.", i-1, i)
+		}
+	}
+
+	// Validate update of the most recent value in the page
+	err = collection.Update(seriesId, -1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	_, values, err = collection.RangeFloat64(seriesId, startTime, time.Now())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if values[len(values)-1] != -1 {
+		t.Errorf("Expected updated value to be -1. Got %v.", values[len(values)-1])
+	}
+
+	if values[len(values)-2] != float64(entryCount-2) {
+		t.Errorf("Update modified the wrong slot. Got %v.", values[len(values)-2])
+	}
+
+	// The latest value is stored as it is in the page
+	err = collection.Append(seriesId, time.Now(), 7)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	latest, err := collection.Latest(seriesId)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var v interface{}
+	if err := latest.GetValue(&v); err != nil || v != 7.0 {
+		t.Errorf("Expected the latest value to be float64(7). Got %T(%v).", v, v)
+	}
+}
+
+func TestNPStartTime(t *testing.T) {
+	database := DBConnect()
+	name := "test_np_start_time"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Create a new series in the collection
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Millisecond)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Entries before the start time should be rejected
+	err = collection.Append(seriesId, startTime.Add(-time.Second), "x")
+	if !errors.Is(err, ErrTooOld) {
+		t.Errorf("Timestamp preceded the series start time but did not cause an error")
+	}
+
+	// The first page should start with the series
+	timestamp := startTime.Add(time.Hour)
+	err = collection.Append(seriesId, timestamp, "x")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	var page dataPage
+	err = database.C(name).Find(bson.M{"seriesid": seriesId}).One(&page)
+	if err != nil {
+		t.Fatalf("Failed to fetch page")
+	}
+
+	if !page.StartTime.Equal(startTime) {
+		t.Errorf("StartTime of the first page (%s) is not equal to the series start time (%s)", page.StartTime.Format(layout), startTime.Format(layout))
+	}
+
+	// Validate series info
+	info, err := collection.Info(seriesId)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !info.StartTime.Equal(startTime) {
+		t.Errorf("Series start time (%s) does not match the created start time (%s)", info.StartTime.Format(layout), startTime.Format(layout))
+	}
+
+	if !info.LastValueTime.Equal(timestamp) {
+		t.Errorf("Series last value time (%s) does not match the appended time (%s)", info.LastValueTime.Format(layout), timestamp.Format(layout))
+	}
+}
+
+func TestNPAutoCreate(t *testing.T) {
+	database := DBConnect()
+	name := "test_np_auto_create"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Appending to a missing series should fail
+	seriesId := bson.NewObjectId()
+	timestamp := time.Now().AddDate(-1, 0, 0).Truncate(time.Millisecond)
+	err = collection.Append(seriesId, timestamp, 1)
+	if !errors.Is(err, ErrSeriesNotFound) {
+		t.Errorf("Expected ErrSeriesNotFound, got %v", err)
+	}
+
+	// The first append creates the series when requested
+	err = collection.AppendWithOptions(seriesId, timestamp, 1, AppendOptions{AutoCreate: true})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	info, err := collection.Info(seriesId)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !info.StartTime.Equal(timestamp) || !info.LastValueTime.Equal(timestamp) {
+		t.Errorf("Expected the series to start with its first entry at %v, got %v", timestamp, info.StartTime)
+	}
+
+	// Subsequent appends behave as usual
+	err = collection.AppendWithOptions(seriesId, timestamp.Add(-time.Second), 2, AppendOptions{AutoCreate: true})
+	if !errors.Is(err, ErrTooOld) {
+		t.Errorf("Expected ErrTooOld, got %v", err)
+	}
+
+	// Series are created by every append when enabled for the collection
+	collection.AutoCreate = true
+	otherId := bson.NewObjectId()
+	for i := 0; i < 100; i++ {
+		err = collection.Append(otherId, timestamp.Add(time.Duration(i)*time.Minute), i)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	points, err := collection.Range(otherId, timestamp, timestamp.Add(time.Hour))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(points) != 61 {
+		t.Errorf("Expected 61 data points, got %d", len(points))
+	}
+
+	stats, err := collection.Stats(otherId)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if stats.Count != 100 || stats.Sum != 4950 {
+		t.Errorf("Expected 100 entries with a sum of 4950, got %d with a sum of %v", stats.Count, stats.Sum)
+	}
+}
@@ -0,0 +1,75 @@
+package mgots
+
+import (
+	"time"
+)
+
+// QueryFunc returns data points of a series between minTime and maxTime. It
+// is typically a closure over Range, Aggregate or Resample.
+type QueryFunc func(minTime time.Time, maxTime time.Time) (DataPoints, error)
+
+// shiftedDataPoint is a data point with a shifted timestamp.
+type shiftedDataPoint struct {
+	DataPoint
+	timestamp time.Time
+}
+
+func (c *shiftedDataPoint) Timestamp() time.Time {
+	return c.timestamp
+}
+
+// Shift returns the given data points with the given duration added to their
+// timestamps.
+func Shift(points DataPoints, d time.Duration) DataPoints {
+	results := make(DataPoints, len(points))
+	for i, point := range points {
+		// shift already shifted data points without nesting them
+		if p, ok := point.(*shiftedDataPoint); ok {
+			results[i] = &shiftedDataPoint{p.DataPoint, p.timestamp.Add(d)}
+			continue
+		}
+
+		results[i] = &shiftedDataPoint{point, point.Timestamp().Add(d)}
+	}
+
+	return results
+}
+
+// Offset returns a QueryFunc which queries the range the given offset
+// earlier, with the timestamps of the results shifted forward by the offset,
+// so they fall within the requested range. E.g. with an offset of one week,
+// the data points of last week are returned at the times of this week.
+func Offset(query QueryFunc, offset time.Duration) QueryFunc {
+	return func(minTime time.Time, maxTime time.Time) (DataPoints, error) {
+		points, err := query(minTime.Add(-offset), maxTime.Add(-offset))
+		if err != nil {
+			return nil, err
+		}
+
+		return Shift(points, offset), nil
+	}
+}
+
+// PeriodOverPeriod returns the data points of the range between minTime and
+// maxTime, followed by the data points of each of the n prior periods of the
+// given duration. The timestamps of prior periods are shifted forward to the
+// same relative timestamps as the current range, for side-by-side comparison.
+//
+// Aggregated and resampled periods are aligned if the period is a multiple of
+// the aggregation interval or step.
+func PeriodOverPeriod(query QueryFunc, minTime time.Time, maxTime time.Time, period time.Duration, n int) ([]DataPoints, error) {
+	if period <= 0 || n < 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	results := make([]DataPoints, n+1)
+	for i := range results {
+		points, err := Offset(query, time.Duration(i)*period)(minTime, maxTime)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = points
+	}
+
+	return results, nil
+}
@@ -0,0 +1,105 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestPeriodOverPeriod(t *testing.T) {
+	database := DBConnect()
+	name := "test_period_over_period"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add the week number as the value of every hour for three weeks
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(24 * time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	week := 7 * 24 * time.Hour
+	for i := 0; i < 3*7*24; i++ {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Hour), float64(i/(7*24)))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// Compare daily sums of the last week with the two prior weeks
+	minTime := startTime.Add(2 * week)
+	maxTime := minTime.Add(week - time.Nanosecond)
+	periods, err := PeriodOverPeriod(func(minTime time.Time, maxTime time.Time) (DataPoints, error) {
+		return collection.Aggregate(seriesId, minTime, maxTime, 24*time.Hour, AggregateSum)
+	}, minTime, maxTime, week, 2)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(periods) != 3 {
+		t.Fatalf("Expected 3 periods, got %d", len(periods))
+	}
+
+	for i, points := range periods {
+		if len(points) != 7 {
+			t.Fatalf("Expected 7 days in period %d, got %d", i, len(points))
+		}
+
+		for j, point := range points {
+			v, err := point.Float64()
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			timestamp := minTime.Add(time.Duration(j) * 24 * time.Hour)
+			expected := float64(24 * (2 - i))
+			if !point.Timestamp().Equal(timestamp) || v != expected {
+				t.Errorf("Expected %v at %s in period %d, got %v at %s", expected, timestamp.Format(layout), i, v, point.Timestamp().Format(layout))
+			}
+		}
+	}
+
+	// Range with an offset of one week
+	points, _, err := collection.RangeWithOptions(seriesId, minTime, minTime.Add(time.Hour), RangeOptions{Offset: week})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 data points, got %d", len(points))
+	}
+
+	for i, point := range points {
+		v, err := point.Float64()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		timestamp := minTime.Add(time.Duration(i) * time.Hour)
+		if !point.Timestamp().Equal(timestamp) || v != 1 {
+			t.Errorf("Expected 1 at %s, got %v at %s", timestamp.Format(layout), v, point.Timestamp().Format(layout))
+		}
+	}
+}
+
+func TestShift(t *testing.T) {
+	timestamp := time.Now().Truncate(time.Second)
+	points := DataPoints{NewFloat64DataPoint(timestamp, 1)}
+
+	// shifting shifted data points adds both shifts
+	points = Shift(Shift(points, time.Hour), time.Minute)
+	expected := timestamp.Add(time.Hour + time.Minute)
+	if !points[0].Timestamp().Equal(expected) {
+		t.Errorf("Expected %s, got %s", expected.Format(layout), points[0].Timestamp().Format(layout))
+	}
+
+	if _, ok := points[0].(*shiftedDataPoint).DataPoint.(*shiftedDataPoint); ok {
+		t.Errorf("Expected shifted data points not to be nested")
+	}
+}
@@ -0,0 +1,173 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"sort"
+	"time"
+)
+
+// Errors
+var ErrInvalidQuantile = errors.New("Quantile must be between 0 and 1")
+
+// Quantile returns the exact q-quantile (0 <= q <= 1) of the numeric values
+// in each interval between minTime and maxTime. Buckets are aligned as per
+// Aggregate and empty buckets are omitted.
+//
+// Every value in the range is decoded, so Quantile is best suited to short
+// ranges. See SketchQuantile for long ranges.
+func (c *NonperiodicCollection) Quantile(seriesId interface{}, minTime time.Time, maxTime time.Time, interval time.Duration, q float64) (DataPoints, error) {
+	if interval <= 0 {
+		return nil, wrapError("Quantile", seriesId, ErrInvalidInterval)
+	}
+
+	if q < 0 || q > 1 {
+		return nil, wrapError("Quantile", seriesId, ErrInvalidQuantile)
+	}
+
+	timestamps, values, err := c.RangeFloat64(seriesId, minTime, maxTime)
+	if err != nil {
+		return nil, wrapError("Quantile", seriesId, err)
+	}
+
+	results := make(DataPoints, 0)
+	for i := 0; i < len(timestamps); {
+		// find all values in this bucket
+		start := timestamps[i].Truncate(interval)
+		j := i + 1
+		for j < len(timestamps) && timestamps[j].Before(start.Add(interval)) {
+			j++
+		}
+
+		// null values are ignored
+		bucket := make([]float64, 0, j-i)
+		for _, v := range values[i:j] {
+			if !math.IsNaN(v) {
+				bucket = append(bucket, v)
+			}
+		}
+
+		if len(bucket) > 0 {
+			sort.Float64s(bucket)
+			results = append(results, newFloat64DataPoint(start, quantile(bucket, q)))
+		}
+		i = j
+	}
+
+	return results, nil
+}
+
+// SketchQuantile returns an estimate of the q-quantile (0 <= q <= 1) of the
+// numeric values in each interval between minTime and maxTime, to within the
+// relative accuracy of SKETCH_ACCURACY. If interval is zero, a single quantile
+// is returned for the entire range, with the timestamp of minTime.
+//
+// Pages which fall entirely within a bucket are summarized from the sketch
+// maintained in the page header by collections with Sketches enabled. Only
+// the values of pages which span the boundary of a bucket, or which have no
+// sketch, are decoded.
+func (c *NonperiodicCollection) SketchQuantile(seriesId interface{}, minTime time.Time, maxTime time.Time, interval time.Duration, q float64) (DataPoints, error) {
+	if interval < 0 {
+		return nil, wrapError("SketchQuantile", seriesId, ErrInvalidInterval)
+	}
+
+	if q < 0 || q > 1 {
+		return nil, wrapError("SketchQuantile", seriesId, ErrInvalidQuantile)
+	}
+
+	// returns the start of the bucket for the given timestamp
+	bucketStart := func(timestamp time.Time) time.Time {
+		if interval == 0 {
+			return minTime
+		}
+		return timestamp.Truncate(interval)
+	}
+
+	// fetch sketches, but not values, of all pages in the range
+	pages, err := c.findPages(seriesId, minTime, maxTime, bson.M{"values": 0, "float64s": 0, "padding": 0})
+	if err != nil {
+		return nil, wrapError("SketchQuantile", seriesId, err)
+	}
+
+	// collate sketches by bucket
+	sketches := make(map[time.Time]*Sketch)
+	starts := make([]time.Time, 0)
+	sketchFor := func(start time.Time) *Sketch {
+		sketch, ok := sketches[start]
+		if !ok {
+			sketch = NewSketch()
+			sketches[start] = sketch
+			starts = append(starts, start)
+		}
+		return sketch
+	}
+
+	decode := make([]interface{}, 0)
+	for _, page := range pages {
+		// a page is covered if all of its entries fall within the range and a
+		// single bucket, and were counted in its sketch
+		covered := page.Sketch != nil &&
+			page.Sketch.Count == int64(page.entries()) &&
+			!page.StartTime.Before(minTime) &&
+			!page.EndTime.After(maxTime) &&
+			bucketStart(page.StartTime).Equal(bucketStart(page.EndTime))
+
+		if covered {
+			sketchFor(bucketStart(page.StartTime)).Merge(page.Sketch)
+		} else {
+			decode = append(decode, page.PageId)
+		}
+	}
+
+	// decode the values of all other pages
+	if len(decode) > 0 {
+		var boundaryPages []dataPage
+		err = c.DBCollection.Find(bson.M{"_id": bson.M{"$in": decode}}).Select(bson.M{"padding": 0}).All(&boundaryPages)
+		if err != nil {
+			return nil, wrapError("SketchQuantile", seriesId, newError(err, "Error fetching boundary pages"))
+		}
+
+		timestamps, values, err := pagesFloat64(boundaryPages)
+		if err != nil {
+			return nil, wrapError("SketchQuantile", seriesId, err)
+		}
+
+		for i, timestamp := range timestamps {
+			if !timestamp.Before(minTime) && !timestamp.After(maxTime) {
+				sketchFor(bucketStart(timestamp)).Add(values[i])
+			}
+		}
+	}
+
+	// compute quantile of each bucket
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+
+	results := make(DataPoints, 0, len(starts))
+	for _, start := range starts {
+		if sketches[start].Count > 0 {
+			results = append(results, newFloat64DataPoint(start, sketches[start].Quantile(q)))
+		}
+	}
+
+	return results, nil
+}
+
+// quantile returns the q-quantile of the given sorted values, linearly
+// interpolated between the closest ranks.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+
+	rank := q * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,97 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestQuantiles(t *testing.T) {
+	database := DBConnect()
+	name := "test_quantiles"
+
+	// Create a nonperiodic collection with page sketches
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	collection.Sketches = true
+
+	// Create a new series with values 1 to 1000, one per second
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i := 1; i <= 1000; i++ {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Second), float64(i))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// Validate exact quantiles
+	maxTime := startTime.Add(time.Hour)
+	data, err := collection.Quantile(seriesId, startTime, maxTime, time.Hour, 0.5)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(data) != 1 {
+		t.Fatalf("Expected 1 quantile, got %d", len(data))
+	}
+
+	if v, _ := data[0].Float64(); v != 500.5 {
+		t.Errorf("Expected exact median of 500.5, got %v", v)
+	}
+
+	// Validate sketch quantiles
+	for _, q := range []float64{0.5, 0.95, 0.99} {
+		data, err := collection.SketchQuantile(seriesId, startTime, maxTime, 0, q)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if len(data) != 1 {
+			t.Fatalf("Expected 1 sketch quantile, got %d", len(data))
+		}
+
+		expected := math.Floor(q*999) + 1
+		if v, _ := data[0].Float64(); math.Abs(v-expected)/expected > SKETCH_ACCURACY {
+			t.Errorf("Expected sketch quantile %v to be within %v of %v, got %v", q, SKETCH_ACCURACY, expected, v)
+		}
+	}
+
+	// Sketches should reflect updated values
+	err = collection.Update(seriesId, 1000000)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	data, err = collection.SketchQuantile(seriesId, startTime, maxTime, 0, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if v, _ := data[0].Float64(); math.Abs(v-1000000)/1000000 > SKETCH_ACCURACY {
+		t.Errorf("Expected maximum sketch value of 1000000, got %v", v)
+	}
+
+	// Null values should be removed from sketches
+	err = collection.Update(seriesId, nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	data, err = collection.SketchQuantile(seriesId, startTime, maxTime, 0, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if v, _ := data[0].Float64(); math.Abs(v-999)/999 > SKETCH_ACCURACY {
+		t.Errorf("Expected maximum sketch value of 999, got %v", v)
+	}
+}
@@ -0,0 +1,153 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenDuration
+	tokenString
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (c token) String() string {
+	if c.kind == tokenEOF {
+		return "end of query"
+	}
+
+	return strconv.Quote(c.text)
+}
+
+// SyntaxError describes a query which cannot be parsed.
+type SyntaxError struct {
+	Offset  int // offset of the error in the query, in bytes
+	Message string
+}
+
+func (c *SyntaxError) Error() string {
+	return fmt.Sprintf("Syntax error at offset %d: %s", c.Offset, c.Message)
+}
+
+func syntaxError(pos int, format string, a ...interface{}) *SyntaxError {
+	return &SyntaxError{
+		Offset:  pos,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+const punctuation = "(){},=+-*/%^|"
+
+// lex splits a query into tokens.
+func lex(s string) ([]token, error) {
+	tokens := make([]token, 0)
+	for pos := 0; pos < len(s); {
+		r, size := utf8.DecodeRuneInString(s[pos:])
+		start := pos
+
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+			continue
+
+		case strings.ContainsRune(punctuation, r):
+			pos += size
+			tokens = append(tokens, token{tokenPunct, s[start:pos], start})
+
+		case r == '"':
+			// find the closing quote
+			pos++
+			for pos < len(s) && s[pos] != '"' {
+				if s[pos] == '\\' {
+					pos++
+				}
+				pos++
+			}
+
+			if pos >= len(s) {
+				return nil, syntaxError(start, "unterminated string")
+			}
+
+			pos++
+			text, err := strconv.Unquote(s[start:pos])
+			if err != nil {
+				return nil, syntaxError(start, "invalid string %s", s[start:pos])
+			}
+			tokens = append(tokens, token{tokenString, text, start})
+
+		case r >= '0' && r <= '9' || r == '.':
+			// numbers, with an optional exponent
+			pos = scan(s, pos, func(r rune) bool { return r >= '0' && r <= '9' || r == '.' })
+			if pos < len(s) && (s[pos] == 'e' || s[pos] == 'E') {
+				exp := pos + 1
+				if exp < len(s) && (s[exp] == '+' || s[exp] == '-') {
+					exp++
+				}
+				if exp < len(s) && s[exp] >= '0' && s[exp] <= '9' {
+					pos = scan(s, exp, unicode.IsDigit)
+				}
+			}
+
+			// numbers followed by a unit are durations (E.g. 1h30m)
+			kind := tokenNumber
+			if pos < len(s) && unicode.IsLetter(rune(s[pos])) {
+				kind = tokenDuration
+				pos = scan(s, pos, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' })
+			}
+			tokens = append(tokens, token{kind, s[start:pos], start})
+
+		case unicode.IsLetter(r) || r == '_':
+			pos = scan(s, pos, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' })
+			tokens = append(tokens, token{tokenIdent, s[start:pos], start})
+
+		default:
+			return nil, syntaxError(start, "unexpected character %q", r)
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "", len(s)}), nil
+}
+
+// scan returns the offset of the first rune from pos which does not match fn.
+func scan(s string, pos int, fn func(r rune) bool) int {
+	for pos < len(s) {
+		r, size := utf8.DecodeRuneInString(s[pos:])
+		if !fn(r) {
+			break
+		}
+		pos += size
+	}
+
+	return pos
+}
+
+// parseDuration parses a Go duration, or a whole number of days or weeks
+// (E.g. 7d or 2w).
+func parseDuration(s string) (time.Duration, error) {
+	if n := len(s) - 1; n > 0 && (s[n] == 'd' || s[n] == 'w') {
+		if v, err := strconv.Atoi(s[:n]); err == nil {
+			d := time.Duration(v) * 24 * time.Hour
+			if s[n] == 'w' {
+				d *= 7
+			}
+			return d, nil
+		}
+	}
+
+	return time.ParseDuration(s)
+}
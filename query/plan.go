@@ -0,0 +1,226 @@
+package query
+
+import (
+	"errors"
+	"github.com/cavaliercoder/mgots"
+	"github.com/cavaliercoder/mgots/expr"
+	"github.com/cavaliercoder/mgots/transform"
+	"math"
+	"time"
+)
+
+// Errors
+var ErrInvalidRange = errors.New("The end of the query range precedes the start")
+var ErrTooManySteps = errors.New("The query range contains too many steps")
+var ErrUnknownTransform = errors.New("Unknown transform or invalid transform arguments")
+
+// Defaults applied by Plan to queries which do not specify a range or step.
+var (
+	DefaultRange = time.Hour // duration of the range if no start is given
+	DefaultSteps = 100       // number of steps in the range if no step is given
+	MaxSteps     = 11000     // maximum number of steps in the range
+)
+
+// Plan is a query which is ready to be executed.
+type Plan struct {
+	MinTime    time.Time
+	MaxTime    time.Time
+	Step       time.Duration
+	Offset     time.Duration
+	Expr       expr.Expr
+	Transforms []transform.Transformer
+}
+
+// Plan resolves the range, step and transforms of a query. Relative times are
+// resolved against the given time.
+func (c *Query) Plan(now time.Time) (*Plan, error) {
+	e, err := planExpr(c.Expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plan{
+		MaxTime: now,
+		Expr:    e,
+		Step:    c.Step,
+		Offset:  c.Offset,
+	}
+
+	if c.To != nil {
+		p.MaxTime = c.To.resolve(now)
+	}
+
+	p.MinTime = p.MaxTime.Add(-DefaultRange)
+	if c.From != nil {
+		p.MinTime = c.From.resolve(now)
+	}
+
+	if p.MaxTime.Before(p.MinTime) {
+		return nil, ErrInvalidRange
+	}
+
+	// divide the range into the default number of steps, rounded up to a
+	// whole second
+	if p.Step == 0 {
+		p.Step = p.MaxTime.Sub(p.MinTime) / time.Duration(DefaultSteps)
+		if p.Step < time.Second {
+			p.Step = time.Second
+		} else if r := p.Step % time.Second; r > 0 {
+			p.Step += time.Second - r
+		}
+	}
+
+	if p.MaxTime.Sub(p.MinTime.Truncate(p.Step))/p.Step >= time.Duration(MaxSteps) {
+		return nil, ErrTooManySteps
+	}
+
+	for _, t := range c.Transforms {
+		tr, err := planTransform(t)
+		if err != nil {
+			return nil, err
+		}
+		p.Transforms = append(p.Transforms, tr)
+	}
+
+	return p, nil
+}
+
+// planExpr validates the function calls of an expression and folds
+// operations on constants into a single constant.
+func planExpr(e expr.Expr) (expr.Expr, error) {
+	var err error
+	switch e := e.(type) {
+	case *expr.BinaryExpr:
+		b := *e
+		if b.LHS, err = planExpr(e.LHS); err != nil {
+			return nil, err
+		}
+		if b.RHS, err = planExpr(e.RHS); err != nil {
+			return nil, err
+		}
+
+		lhs, lok := b.LHS.(*expr.ScalarExpr)
+		rhs, rok := b.RHS.(*expr.ScalarExpr)
+		if lok && rok {
+			v, err := b.Op.Apply(lhs.Value, rhs.Value)
+			if err != nil {
+				return nil, err
+			}
+			return expr.Scalar(v), nil
+		}
+		return &b, nil
+
+	case *expr.AggregateExpr:
+		a := *e
+		if a.Expr, err = planExpr(e.Expr); err != nil {
+			return nil, err
+		}
+		return &a, nil
+
+	case *expr.FuncExpr:
+		fn, ok := expr.Functions[e.Name]
+		if !ok {
+			return nil, expr.ErrUnknownFunction
+		}
+
+		if len(e.Args) != fn.Args {
+			return nil, expr.ErrArgumentCount
+		}
+
+		f := &expr.FuncExpr{Name: e.Name, Args: make([]expr.Expr, len(e.Args))}
+		for i, arg := range e.Args {
+			if f.Args[i], err = planExpr(arg); err != nil {
+				return nil, err
+			}
+		}
+		return f, nil
+	}
+
+	return e, nil
+}
+
+// planTransform returns the transformer for a transform call.
+func planTransform(t Transform) (transform.Transformer, error) {
+	if len(t.Args) != 1 {
+		return nil, ErrUnknownTransform
+	}
+
+	n, isNumber := t.Args[0].(float64)
+	d, isDuration := t.Args[0].(time.Duration)
+	count := int(n)
+	isCount := isNumber && n == float64(count)
+
+	switch {
+	case t.Name == "moving_avg" && isCount:
+		return transform.NewMovingAverage(count)
+	case t.Name == "moving_avg" && isDuration:
+		return transform.NewTimeMovingAverage(d)
+	case t.Name == "rolling_min" && isCount:
+		return transform.NewRollingMin(count)
+	case t.Name == "rolling_min" && isDuration:
+		return transform.NewTimeRollingMin(d)
+	case t.Name == "rolling_max" && isCount:
+		return transform.NewRollingMax(count)
+	case t.Name == "rolling_max" && isDuration:
+		return transform.NewTimeRollingMax(d)
+	case t.Name == "ewma" && isNumber && n > 0 && n <= 1:
+		return transform.NewEWMA(n), nil
+	case t.Name == "ewma_span" && isCount && count > 0:
+		return transform.NewEWMASpan(count), nil
+	}
+
+	return nil, ErrUnknownTransform
+}
+
+// Execute evaluates the planned query against the given collection, over the
+// range the offset of the plan earlier. Each transform is applied in order to
+// the values of every resulting series, skipping steps without a value.
+func (c *Plan) Execute(collection mgots.Collection) (*expr.Result, error) {
+	ctx := &expr.Context{
+		Collection: collection,
+		MinTime:    c.MinTime.Add(-c.Offset),
+		MaxTime:    c.MaxTime.Add(-c.Offset),
+		Step:       c.Step,
+	}
+
+	result, err := expr.Evaluate(ctx, c.Expr)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Offset != 0 {
+		timestamps := make([]time.Time, len(result.Timestamps))
+		for i, timestamp := range result.Timestamps {
+			timestamps[i] = timestamp.Add(c.Offset)
+		}
+		result.Timestamps = timestamps
+	}
+
+	for _, t := range c.Transforms {
+		for _, series := range result.Series {
+			t.Reset()
+			for i, v := range series.Values {
+				if !math.IsNaN(v) {
+					series.Values[i] = t.Next(result.Timestamps[i], v)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Run parses, plans and executes a query against the given collection.
+func Run(collection mgots.Collection, s string, now time.Time) (*expr.Result, error) {
+	q, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := q.Plan(now)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Execute(collection)
+}
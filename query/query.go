@@ -0,0 +1,527 @@
+// Package query implements a compact text query language for mgots time
+// series, for use by command-line tools and HTTP APIs.
+//
+// A query is an expression, followed by optional clauses for the time range,
+// the step to which series are resampled, an offset and transforms applied to
+// each resulting series:
+//
+//	sum by (host) ({metric="errors"}) / on (host) sum by (host) ({metric="requests"}) * 100
+//	    from now-1d to now step 5m | ewma(0.3)
+//
+// Expressions are written as printed by the expr package. Series are selected
+// by tags with {name="value", ...}, or referenced by ID with series("id"),
+// where IDs which are 24 hexadecimal digits are ObjectIds. The sum, avg, min,
+// max and count aggregations may group series with "by (tag, ...)", and
+// binary operators may pair series by a subset of tags with "on (tag, ...)".
+//
+// An offset evaluates the query over the range the given duration earlier,
+// with the resulting timestamps shifted forward into the requested range (E.g.
+// "offset 1w" returns last week's values at this week's times).
+//
+// Times are quoted RFC 3339 timestamps, "now", or an offset from now (E.g.
+// now-1h or -1h). Durations are Go durations, or a whole number of days or
+// weeks (E.g. 7d or 2w).
+//
+// Transforms are moving_avg, rolling_min and rolling_max, which accept a
+// number of values or a duration, ewma, which accepts a smoothing factor, and
+// ewma_span, which accepts a number of values.
+package query
+
+import (
+	"github.com/cavaliercoder/mgots"
+	"github.com/cavaliercoder/mgots/expr"
+	"gopkg.in/mgo.v2/bson"
+	"strconv"
+	"time"
+)
+
+// Query is a parsed query.
+type Query struct {
+	Expr       expr.Expr
+	From       *Time         // start of the range, or nil for the default
+	To         *Time         // end of the range, or nil for now
+	Step       time.Duration // step of the range, or zero for the default
+	Offset     time.Duration // evaluate the range this much earlier
+	Transforms []Transform
+}
+
+// Time is an absolute time, or a time relative to when a query is planned.
+type Time struct {
+	Time     time.Time     // absolute time, if not Relative
+	Relative bool          // true if the time is relative to now
+	Offset   time.Duration // offset from now, if Relative
+}
+
+func (c *Time) resolve(now time.Time) time.Time {
+	if c.Relative {
+		return now.Add(c.Offset)
+	}
+
+	return c.Time
+}
+
+// Transform is a call to a transform applied to each resulting series.
+type Transform struct {
+	Name string
+	Args []interface{} // float64 and time.Duration arguments
+}
+
+// parser is a recursive descent parser of the tokens of a query.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses the given query text.
+func Parse(s string) (*Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	q := &Query{}
+	if q.Expr, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.next()
+		switch {
+		case tok.kind == tokenEOF:
+			return q, nil
+
+		case tok.kind == tokenIdent && tok.text == "from":
+			if q.From, err = p.parseTime(); err != nil {
+				return nil, err
+			}
+
+		case tok.kind == tokenIdent && tok.text == "to":
+			if q.To, err = p.parseTime(); err != nil {
+				return nil, err
+			}
+
+		case tok.kind == tokenIdent && tok.text == "step":
+			if q.Step, err = p.parseDuration(); err != nil {
+				return nil, err
+			}
+
+		case tok.kind == tokenIdent && tok.text == "offset":
+			if q.Offset, err = p.parseDuration(); err != nil {
+				return nil, err
+			}
+
+		case p.is(tok, "|"):
+			t, err := p.parseTransform()
+			if err != nil {
+				return nil, err
+			}
+			q.Transforms = append(q.Transforms, *t)
+
+		default:
+			return nil, syntaxError(tok.pos, "unexpected %s", tok)
+		}
+	}
+}
+
+func (c *parser) peek() token {
+	return c.tokens[c.pos]
+}
+
+func (c *parser) next() token {
+	tok := c.tokens[c.pos]
+	if tok.kind != tokenEOF {
+		c.pos++
+	}
+
+	return tok
+}
+
+// is returns true if the token is the given punctuation.
+func (c *parser) is(tok token, punct string) bool {
+	return tok.kind == tokenPunct && tok.text == punct
+}
+
+// accept consumes the next token if it is the given punctuation.
+func (c *parser) accept(punct string) bool {
+	if c.is(c.peek(), punct) {
+		c.pos++
+		return true
+	}
+
+	return false
+}
+
+func (c *parser) expect(punct string) error {
+	if tok := c.next(); !c.is(tok, punct) {
+		return syntaxError(tok.pos, "expected %q, found %s", punct, tok)
+	}
+
+	return nil
+}
+
+func (c *parser) expectKind(kind tokenKind, name string) (token, error) {
+	tok := c.next()
+	if tok.kind != kind {
+		return tok, syntaxError(tok.pos, "expected %s, found %s", name, tok)
+	}
+
+	return tok, nil
+}
+
+// binary operators by precedence
+var precedence = [][]expr.Operator{
+	{expr.OpAdd, expr.OpSub},
+	{expr.OpMul, expr.OpDiv, expr.OpMod},
+}
+
+func (c *parser) parseExpr() (expr.Expr, error) {
+	return c.parseBinary(0)
+}
+
+// parseBinary parses left associative binary operators of the given
+// precedence level and higher.
+func (c *parser) parseBinary(level int) (expr.Expr, error) {
+	if level == len(precedence) {
+		return c.parseUnary()
+	}
+
+	lhs, err := c.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := c.acceptOperator(precedence[level])
+		if !ok {
+			return lhs, nil
+		}
+
+		on, err := c.parseOn()
+		if err != nil {
+			return nil, err
+		}
+
+		rhs, err := c.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = &expr.BinaryExpr{Op: op, LHS: lhs, RHS: rhs, On: on}
+	}
+}
+
+// parsePower parses the right associative power operator, which binds more
+// tightly than unary minus on its left (E.g. -2 ^ 2 is -(2 ^ 2)).
+func (c *parser) parsePower() (expr.Expr, error) {
+	lhs, err := c.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := c.acceptOperator([]expr.Operator{expr.OpPow}); !ok {
+		return lhs, nil
+	}
+
+	on, err := c.parseOn()
+	if err != nil {
+		return nil, err
+	}
+
+	rhs, err := c.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &expr.BinaryExpr{Op: expr.OpPow, LHS: lhs, RHS: rhs, On: on}, nil
+}
+
+func (c *parser) acceptOperator(ops []expr.Operator) (expr.Operator, bool) {
+	for _, op := range ops {
+		if c.accept(op.String()) {
+			return op, true
+		}
+	}
+
+	return 0, false
+}
+
+func (c *parser) parseUnary() (expr.Expr, error) {
+	if !c.accept("-") {
+		return c.parsePower()
+	}
+
+	e, err := c.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	if scalar, ok := e.(*expr.ScalarExpr); ok {
+		return expr.Scalar(-scalar.Value), nil
+	}
+
+	return expr.Binary(expr.OpSub, expr.Scalar(0), e), nil
+}
+
+func (c *parser) parsePrimary() (expr.Expr, error) {
+	tok := c.next()
+	switch {
+	case tok.kind == tokenNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, syntaxError(tok.pos, "invalid number %s", tok)
+		}
+		return expr.Scalar(v), nil
+
+	case c.is(tok, "("):
+		e, err := c.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return e, c.expect(")")
+
+	case c.is(tok, "{"):
+		return c.parseSelector()
+
+	case tok.kind == tokenIdent && tok.text == "series":
+		if err := c.expect("("); err != nil {
+			return nil, err
+		}
+
+		id, err := c.expectKind(tokenString, "series ID")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := c.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return expr.Ref(parseSeriesId(id.text)), nil
+
+	case tok.kind == tokenIdent:
+		if aggregator, err := mgots.ParseAggregator(tok.text); err == nil {
+			return c.parseAggregate(aggregator)
+		}
+
+		if err := c.expect("("); err != nil {
+			return nil, err
+		}
+
+		args := make([]expr.Expr, 0)
+		for !c.accept(")") {
+			if len(args) > 0 {
+				if err := c.expect(","); err != nil {
+					return nil, err
+				}
+			}
+
+			arg, err := c.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+
+		return expr.Func(tok.text, args...), nil
+	}
+
+	return nil, syntaxError(tok.pos, "unexpected %s", tok)
+}
+
+// parseSelector parses the tags of a selector, following the opening brace.
+func (c *parser) parseSelector() (expr.Expr, error) {
+	tags := make(map[string]string)
+	for !c.accept("}") {
+		if len(tags) > 0 {
+			if err := c.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		name, err := c.expectKind(tokenIdent, "tag name")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := c.expect("="); err != nil {
+			return nil, err
+		}
+
+		value, err := c.expectKind(tokenString, "tag value")
+		if err != nil {
+			return nil, err
+		}
+
+		tags[name.text] = value.text
+	}
+
+	return expr.Select(tags), nil
+}
+
+// parseAggregate parses an aggregation, following the aggregator name.
+func (c *parser) parseAggregate(aggregator mgots.Aggregator) (expr.Expr, error) {
+	var by []string
+	if tok := c.peek(); tok.kind == tokenIdent && tok.text == "by" {
+		c.next()
+
+		var err error
+		if by, err = c.parseTagList(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.expect("("); err != nil {
+		return nil, err
+	}
+
+	e, err := c.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return expr.Aggregate(aggregator, e, by...), nil
+}
+
+// parseOn parses the optional tags on which a binary operator pairs series.
+func (c *parser) parseOn() ([]string, error) {
+	if tok := c.peek(); tok.kind != tokenIdent || tok.text != "on" {
+		return nil, nil
+	}
+	c.next()
+
+	return c.parseTagList()
+}
+
+// parseTagList parses a parenthesized list of tag names.
+func (c *parser) parseTagList() ([]string, error) {
+	if err := c.expect("("); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for !c.accept(")") {
+		if len(names) > 0 {
+			if err := c.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		name, err := c.expectKind(tokenIdent, "tag name")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name.text)
+	}
+
+	return names, nil
+}
+
+func (c *parser) parseTime() (*Time, error) {
+	tok := c.next()
+	switch {
+	case tok.kind == tokenString:
+		t, err := time.Parse(time.RFC3339Nano, tok.text)
+		if err != nil {
+			return nil, syntaxError(tok.pos, "invalid time %s", tok)
+		}
+		return &Time{Time: t}, nil
+
+	case tok.kind == tokenIdent && tok.text == "now":
+		sign := time.Duration(0)
+		if c.accept("+") {
+			sign = 1
+		} else if c.accept("-") {
+			sign = -1
+		}
+
+		if sign == 0 {
+			return &Time{Relative: true}, nil
+		}
+
+		d, err := c.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+		return &Time{Relative: true, Offset: sign * d}, nil
+
+	case c.is(tok, "-"):
+		d, err := c.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+		return &Time{Relative: true, Offset: -d}, nil
+	}
+
+	return nil, syntaxError(tok.pos, "expected time, found %s", tok)
+}
+
+func (c *parser) parseDuration() (time.Duration, error) {
+	tok, err := c.expectKind(tokenDuration, "duration")
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := parseDuration(tok.text)
+	if err != nil || d <= 0 {
+		return 0, syntaxError(tok.pos, "invalid duration %s", tok)
+	}
+
+	return d, nil
+}
+
+func (c *parser) parseTransform() (*Transform, error) {
+	name, err := c.expectKind(tokenIdent, "transform name")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transform{Name: name.text, Args: make([]interface{}, 0)}
+	if err := c.expect("("); err != nil {
+		return nil, err
+	}
+
+	for !c.accept(")") {
+		if len(t.Args) > 0 {
+			if err := c.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		switch tok := c.peek(); tok.kind {
+		case tokenNumber:
+			c.next()
+			v, err := strconv.ParseFloat(tok.text, 64)
+			if err != nil {
+				return nil, syntaxError(tok.pos, "invalid number %s", tok)
+			}
+			t.Args = append(t.Args, v)
+
+		case tokenDuration:
+			d, err := c.parseDuration()
+			if err != nil {
+				return nil, err
+			}
+			t.Args = append(t.Args, d)
+
+		default:
+			return nil, syntaxError(tok.pos, "expected number or duration, found %s", tok)
+		}
+	}
+
+	return t, nil
+}
+
+// parseSeriesId returns an ObjectId for 24 digit hexadecimal series IDs, or
+// otherwise the given string.
+func parseSeriesId(s string) interface{} {
+	if bson.IsObjectIdHex(s) {
+		return bson.ObjectIdHex(s)
+	}
+
+	return s
+}
@@ -0,0 +1,206 @@
+package query
+
+import (
+	"github.com/cavaliercoder/mgots"
+	"github.com/cavaliercoder/mgots/expr"
+	"github.com/cavaliercoder/mgots/transform"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"testing"
+	"time"
+)
+
+var startTime = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testCollection serves series of values at one minute steps from memory.
+type testCollection struct {
+	mgots.Collection
+	tags   map[string]map[string]string
+	values map[string][]float64
+}
+
+func (c *testCollection) Info(seriesId interface{}) (*mgots.SeriesInfo, error) {
+	return &mgots.SeriesInfo{SeriesId: seriesId, Tags: c.tags[seriesId.(string)]}, nil
+}
+
+func (c *testCollection) FindSeries(tags map[string]string) ([]interface{}, error) {
+	ids := make([]interface{}, 0)
+	for _, id := range []string{"a", "b"} {
+		if c.tags[id]["metric"] == tags["metric"] {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (c *testCollection) Resample(seriesId interface{}, minTime time.Time, maxTime time.Time, step time.Duration, fill mgots.FillPolicy) (mgots.DataPoints, error) {
+	points := make(mgots.DataPoints, 0)
+	for i, v := range c.values[seriesId.(string)] {
+		timestamp := startTime.Add(time.Duration(i) * step)
+		if !timestamp.Before(minTime) && !timestamp.After(maxTime) {
+			points = append(points, mgots.NewFloat64DataPoint(timestamp, v))
+		}
+	}
+
+	return points, nil
+}
+
+func TestParse(t *testing.T) {
+	oid := bson.NewObjectId()
+	tests := map[string]string{
+		`1 + 2 * 3`:                        `(1 + (2 * 3))`,
+		`(1 + 2) * -3`:                     `((1 + 2) * -3)`,
+		`2 ^ 3 ^ 2`:                        `(2 ^ (3 ^ 2))`,
+		`-2 ^ 2`:                           `(0 - (2 ^ 2))`,
+		`2 ^ -1`:                           `(2 ^ -1)`,
+		`-series("abc")`:                   `(0 - series("abc"))`,
+		`series("` + oid.Hex() + `") / 2`:  `(series("` + oid.Hex() + `") / 2)`,
+		`{metric="cpu",host="a"}`:          `{host="a", metric="cpu"}`,
+		`sum by (host) ({metric="cpu"})`:   `sum by (host) ({metric="cpu"})`,
+		`a / on (host) b`:                  ``,
+		`{a="x"} / on (host) {b="y"} % 10`: `(({a="x"} / on (host) {b="y"}) % 10)`,
+		`clamp_max(abs({m="x"}), 1e2)`:     `clamp_max(abs({m="x"}), 100)`,
+		`max({}) from now-1h to now step 1m | ewma(0.5)`: `max ({})`,
+	}
+
+	for s, expected := range tests {
+		q, err := Parse(s)
+		if expected == "" {
+			if _, ok := err.(*SyntaxError); !ok {
+				t.Errorf("Expected syntax error for %s, got %v", s, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Error parsing %s: %s", s, err.Error())
+			continue
+		}
+
+		if q.Expr.String() != expected {
+			t.Errorf("Expected %s to parse as %s, got %s", s, expected, q.Expr.String())
+		}
+	}
+
+	// clauses
+	q, err := Parse(`{m="x"} from "2015-01-01T00:00:00Z" to now-2d step 90s | moving_avg(5m) | rolling_max(3)`)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if q.From == nil || q.From.Relative || !q.From.Time.Equal(startTime) {
+		t.Errorf("Expected absolute start time, got %+v", q.From)
+	}
+
+	if q.To == nil || !q.To.Relative || q.To.Offset != -48*time.Hour {
+		t.Errorf("Expected relative end time, got %+v", q.To)
+	}
+
+	if q.Step != 90*time.Second {
+		t.Errorf("Expected step of 90s, got %v", q.Step)
+	}
+
+	if len(q.Transforms) != 2 || q.Transforms[0].Args[0] != 5*time.Minute || q.Transforms[1].Args[0] != 3.0 {
+		t.Errorf("Expected two transforms, got %+v", q.Transforms)
+	}
+
+	// syntax errors
+	for _, s := range []string{``, `1 +`, `{m=x}`, `sum by host ({})`, `1 step`, `1 | ewma`, `"abc`, `1 # 2`, `1 from yesterday`} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Expected syntax error for %q", s)
+		} else if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Expected syntax error for %q, got %v", s, err)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	now := startTime.Add(24 * time.Hour)
+	q, err := Parse(`{m="x"} * (2 + 3)`)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	p, err := q.Plan(now)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !p.MaxTime.Equal(now) || !p.MinTime.Equal(now.Add(-DefaultRange)) || p.Step != 36*time.Second {
+		t.Errorf("Unexpected default range %s to %s step %v", p.MinTime, p.MaxTime, p.Step)
+	}
+
+	if s := p.Expr.String(); s != `({m="x"} * 5)` {
+		t.Errorf("Expected constants to be folded, got %s", s)
+	}
+
+	for s, expected := range map[string]error{
+		`{} from now to now-1h`: ErrInvalidRange,
+		`{} from -30d step 1s`:  ErrTooManySteps,
+		`{} | ewma(2)`:          ErrUnknownTransform,
+		`{} | moving_avg(1.5)`:  ErrUnknownTransform,
+		`{} | moving_avg(0)`:    transform.ErrInvalidWindow,
+		`{} | nope(1)`:          ErrUnknownTransform,
+		`nope({})`:              nil,
+		`clamp_min({})`:         nil,
+	} {
+		q, err := Parse(s)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if _, err := q.Plan(now); err == nil || expected != nil && err != expected {
+			t.Errorf("Expected error planning %s, got %v", s, err)
+		}
+	}
+
+	// durations are positive when parsed, but may be given directly
+	q = &Query{Expr: expr.Scalar(1), Transforms: []Transform{{Name: "moving_avg", Args: []interface{}{time.Duration(0)}}}}
+	if _, err := q.Plan(now); err != transform.ErrInvalidWindow {
+		t.Errorf("Expected ErrInvalidWindow for a window of zero duration, got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	collection := &testCollection{
+		tags: map[string]map[string]string{
+			"a": {"metric": "cpu", "host": "x"},
+			"b": {"metric": "cpu", "host": "y"},
+		},
+		values: map[string][]float64{
+			"a": {1, 2, 3, 4},
+			"b": {3, math.NaN(), 5, 8},
+		},
+	}
+
+	result, err := Run(collection, `sum({metric="cpu"}) * 10 from "2015-01-01T00:00:00Z" to "2015-01-01T00:03:00Z" step 1m | moving_avg(2)`, time.Now())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(result.Series) != 1 {
+		t.Fatalf("Expected 1 series, got %d", len(result.Series))
+	}
+
+	expected := []float64{40, 30, 50, 100}
+	for i, v := range result.Series[0].Values {
+		if v != expected[i] {
+			t.Errorf("Expected %v at step %d, got %v", expected[i], i, v)
+		}
+	}
+
+	// shift the values of series a forward by two minutes
+	result, err = Run(collection, `series("a") from "2015-01-01T00:02:00Z" to "2015-01-01T00:05:00Z" step 1m offset 2m`, time.Now())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected = []float64{1, 2, 3, 4}
+	for i, v := range result.Series[0].Values {
+		timestamp := startTime.Add(time.Duration(i+2) * time.Minute)
+		if v != expected[i] || !result.Timestamps[i].Equal(timestamp) {
+			t.Errorf("Expected %v at %s, got %v at %s", expected[i], timestamp, v, result.Timestamps[i])
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package mgots
+
+import (
+	"encoding/base64"
+	"errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+)
+
+// Errors
+var ErrInvalidToken = errors.New("Invalid or expired continuation token")
+
+// RangeOptions controls the order and number of data points returned by
+// RangeWithOptions.
+type RangeOptions struct {
+	Descending bool          // return data points in reverse chronological order
+	Limit      int           // maximum number of data points to return, or zero for no limit
+	Token      string        // continuation token returned by a previous request
+	Offset     time.Duration // return the range this much earlier, shifted forward to the requested times
+}
+
+// rangeToken identifies the page and slot of the next data point to be
+// returned by a paginated range request.
+type rangeToken struct {
+	PageId interface{} `bson:"p"`
+	Slot   int         `bson:"s"`
+}
+
+func (c rangeToken) String() string {
+	b, err := bson.Marshal(c)
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseRangeToken(s string) (*rangeToken, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	token := &rangeToken{}
+	if err := bson.Unmarshal(b, token); err != nil || token.PageId == nil || token.Slot < 0 {
+		return nil, ErrInvalidToken
+	}
+
+	return token, nil
+}
+
+// RangeWithOptions returns the data points between minTime and maxTime in the
+// order and up to the limit given in options.
+//
+// If an offset is given, the range the offset earlier is returned with the
+// timestamps of each data point shifted forward by the offset.
+//
+// If the limit is reached before the end of the range, a continuation token
+// is returned which may be given in the options of a subsequent request, with
+// the same range and order, to return the following data points. The token is
+// empty once the range is exhausted.
+func (c *NonperiodicCollection) RangeWithOptions(seriesId interface{}, minTime time.Time, maxTime time.Time, options RangeOptions) (DataPoints, string, error) {
+	points, token, err := c.rangeWithOptions(seriesId, minTime.Add(-options.Offset), maxTime.Add(-options.Offset), options)
+	if err != nil || options.Offset == 0 {
+		return points, token, err
+	}
+
+	return Shift(points, options.Offset), token, nil
+}
+
+func (c *NonperiodicCollection) rangeWithOptions(seriesId interface{}, minTime time.Time, maxTime time.Time, options RangeOptions) (DataPoints, string, error) {
+	starttime := bson.M{"$lte": maxTime}
+	sort := "starttime"
+	if options.Descending {
+		sort = "-starttime"
+	}
+
+	// resume from the page identified by the continuation token
+	var start *rangeToken
+	if options.Token != "" {
+		token, err := parseRangeToken(options.Token)
+		if err != nil {
+			return nil, "", wrapError("RangeWithOptions", seriesId, err)
+		}
+
+		var page dataPage
+		err = c.DBCollection.Find(bson.M{
+			"_id":      token.PageId,
+			"seriesid": seriesId,
+		}).Select(bson.M{"starttime": 1}).One(&page)
+		if err != nil {
+			if err == mgo.ErrNotFound {
+				return nil, "", wrapError("RangeWithOptions", seriesId, ErrInvalidToken)
+			}
+
+			return nil, "", wrapError("RangeWithOptions", seriesId, newError(err, "Error searching for time series page"))
+		}
+
+		if options.Descending {
+			if page.StartTime.Before(maxTime) {
+				starttime["$lte"] = page.StartTime
+			}
+		} else {
+			starttime["$gte"] = page.StartTime
+		}
+
+		start = token
+	}
+
+	iter := c.DBCollection.Find(bson.M{
+		"seriesid":  seriesId,
+		"starttime": starttime,
+		"endtime":   bson.M{"$gte": minTime},
+	}).Select(bson.M{"padding": 0}).Sort(sort).Iter()
+
+	results := DataPoints{}
+	var page dataPage
+	for iter.Next(&page) {
+		// entries are stored in reverse order
+		first, last, step := len(page.Timestamps)-1, -1, -1
+		if options.Descending {
+			first, last, step = 0, len(page.Timestamps), 1
+		}
+
+		// skip pages which precede the token
+		if start != nil {
+			if page.PageId != start.PageId {
+				page = dataPage{}
+				continue
+			}
+
+			if start.Slot >= len(page.Timestamps) {
+				iter.Close()
+				return nil, "", wrapError("RangeWithOptions", seriesId, ErrInvalidToken)
+			}
+
+			first = start.Slot
+			start = nil
+		}
+
+		for i := first; i != last; i += step {
+			timestamp := page.Timestamps[i]
+			if timestamp.IsZero() || timestamp.Before(minTime) || timestamp.After(maxTime) {
+				continue
+			}
+
+			if options.Limit > 0 && len(results) == options.Limit {
+				iter.Close()
+				return results, rangeToken{page.PageId, i}.String(), nil
+			}
+
+			results = append(results, page.point(i))
+		}
+
+		page = dataPage{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, "", wrapError("RangeWithOptions", seriesId, newError(err, "Error searching for time series pages"))
+	}
+
+	// the page identified by the token is no longer in the range
+	if start != nil {
+		return nil, "", wrapError("RangeWithOptions", seriesId, ErrInvalidToken)
+	}
+
+	return results, "", nil
+}
@@ -0,0 +1,95 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestRangeWithOptions(t *testing.T) {
+	database := DBConnect()
+	name := "test_range_options"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add a value every minute across many pages
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	entryCount := 1000
+	for i := 0; i < entryCount; i++ {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Minute), float64(i))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// page through entries 100 to 899 in both directions
+	minTime := startTime.Add(100 * time.Minute)
+	maxTime := startTime.Add(899 * time.Minute)
+	for _, descending := range []bool{false, true} {
+		options := RangeOptions{
+			Descending: descending,
+			Limit:      33,
+		}
+
+		var points DataPoints
+		requests := 0
+		for {
+			page, token, err := collection.RangeWithOptions(seriesId, minTime, maxTime, options)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			if len(page) > options.Limit {
+				t.Fatalf("Expected at most %d data points, got %d", options.Limit, len(page))
+			}
+
+			points = append(points, page...)
+			requests++
+			if token == "" {
+				break
+			}
+			options.Token = token
+		}
+
+		if requests != 25 {
+			t.Errorf("Expected 25 requests, got %d", requests)
+		}
+
+		if len(points) != 800 {
+			t.Fatalf("Expected 800 data points, got %d", len(points))
+		}
+
+		for i, point := range points {
+			expected := 100 + i
+			if descending {
+				expected = 899 - i
+			}
+
+			v, err := point.Float64()
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			if v != float64(expected) || !point.Timestamp().Equal(startTime.Add(time.Duration(expected)*time.Minute)) {
+				t.Fatalf("Expected data point %d at index %d (descending: %v), got %v", expected, i, descending, v)
+			}
+		}
+	}
+
+	// invalid token
+	_, _, err = collection.RangeWithOptions(seriesId, minTime, maxTime, RangeOptions{Token: "invalid"})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"strings"
+	"time"
+)
+
+// FillPolicy determines the value of each step of a resampled range which
+// contains no data points.
+type FillPolicy int
+
+const (
+	FillNull     FillPolicy = iota // Empty steps have a null value
+	FillPrevious                   // Empty steps repeat the value of the previous step
+	FillLinear                     // Empty steps are linearly interpolated between their neighbours
+	FillZero                       // Empty steps have a value of zero
+)
+
+var fillPolicyNames = map[FillPolicy]string{
+	FillNull:     "null",
+	FillPrevious: "previous",
+	FillLinear:   "linear",
+	FillZero:     "zero",
+}
+
+// Errors
+var ErrUnknownFillPolicy = errors.New("Unknown fill policy")
+
+// ParseFillPolicy returns the FillPolicy with the given name (E.g. "linear").
+func ParseFillPolicy(name string) (FillPolicy, error) {
+	name = strings.ToLower(name)
+	for f, n := range fillPolicyNames {
+		if n == name {
+			return f, nil
+		}
+	}
+
+	return 0, ErrUnknownFillPolicy
+}
+
+func (c FillPolicy) String() string {
+	if name, ok := fillPolicyNames[c]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// Resample returns one data point for every step between minTime and maxTime,
+// aligned to multiples of the step since the zero time. The value of each step
+// is the average of the numeric values which fall within it, and steps with
+// no values are filled according to the given fill policy.
+//
+// Entries in the pages which overlap the range, but precede minTime or follow
+// maxTime, are used to fill the first and last steps of the range.
+func (c *NonperiodicCollection) Resample(seriesId interface{}, minTime time.Time, maxTime time.Time, step time.Duration, fill FillPolicy) (DataPoints, error) {
+	if step <= 0 {
+		return nil, wrapError("Resample", seriesId, ErrInvalidInterval)
+	}
+
+	if _, ok := fillPolicyNames[fill]; !ok {
+		return nil, wrapError("Resample", seriesId, ErrUnknownFillPolicy)
+	}
+
+	// walk all pages which overlap the range
+	pages, err := c.findPages(seriesId, minTime, maxTime, bson.M{"padding": 0})
+	if err != nil {
+		return nil, wrapError("Resample", seriesId, err)
+	}
+
+	timestamps, values, err := pagesFloat64(pages)
+	if err != nil {
+		return nil, wrapError("Resample", seriesId, err)
+	}
+
+	return resample(timestamps, values, minTime, maxTime, fixedBuckets(step), fill), nil
+}
+
+// resample converts chronologically ordered values into the buckets between
+// minTime and maxTime.
+func resample(timestamps []time.Time, values []float64, minTime time.Time, maxTime time.Time, b bucketing, fill FillPolicy) DataPoints {
+	if maxTime.Before(minTime) {
+		return DataPoints{}
+	}
+
+	// find the start of each step
+	starts := make([]time.Time, 0)
+	for start := b.start(minTime); !start.After(maxTime); start = b.next(start) {
+		starts = append(starts, start)
+	}
+	steps := len(starts)
+
+	// average the values in each step and find the known neighbours of the
+	// range
+	buckets := make([]bucket, steps)
+	var before, after *sample
+	j := 0
+	for i, timestamp := range timestamps {
+		// null and non-numeric entries are skipped, as per aggregation.add
+		if math.IsNaN(values[i]) {
+			continue
+		}
+
+		if timestamp.Before(starts[0]) {
+			before = &sample{timestamp, values[i]}
+			continue
+		}
+
+		if timestamp.After(maxTime) {
+			if after == nil {
+				after = &sample{timestamp, values[i]}
+			}
+			continue
+		}
+
+		for j+1 < steps && !timestamp.Before(starts[j+1]) {
+			j++
+		}
+		buckets[j].add(values[i])
+	}
+
+	// find the next known value after each step for linear interpolation
+	var next []*sample
+	if fill == FillLinear {
+		next = make([]*sample, steps)
+		for i, n := steps-1, after; i >= 0; i-- {
+			next[i] = n
+			if buckets[i].count > 0 {
+				n = &sample{starts[i], buckets[i].value(AggregateAvg)}
+			}
+		}
+	}
+
+	// fill each step
+	results := make(DataPoints, steps)
+	prev := before
+	for i := range buckets {
+		timestamp := starts[i]
+		if buckets[i].count > 0 {
+			v := buckets[i].value(AggregateAvg)
+			results[i] = newFloat64DataPoint(timestamp, v)
+			prev = &sample{timestamp, v}
+			continue
+		}
+
+		switch fill {
+		case FillZero:
+			results[i] = newFloat64DataPoint(timestamp, 0)
+			continue
+
+		case FillPrevious:
+			if prev != nil {
+				results[i] = newFloat64DataPoint(timestamp, prev.value)
+				continue
+			}
+
+		case FillLinear:
+			if prev != nil && next[i] != nil {
+				results[i] = newFloat64DataPoint(timestamp, prev.interpolate(next[i], timestamp))
+				continue
+			}
+		}
+
+		results[i] = newNullDataPoint(timestamp)
+	}
+
+	return results
+}
+
+// sample is a single numeric value at a point in time.
+type sample struct {
+	timestamp time.Time
+	value     float64
+}
+
+// interpolate returns the value at the given timestamp on the line between
+// this sample and the next.
+func (c *sample) interpolate(next *sample, timestamp time.Time) float64 {
+	span := next.timestamp.Sub(c.timestamp)
+	if span <= 0 {
+		return c.value
+	}
+
+	ratio := float64(timestamp.Sub(c.timestamp)) / float64(span)
+	return c.value + (next.value-c.value)*ratio
+}
@@ -0,0 +1,100 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestResample(t *testing.T) {
+	database := DBConnect()
+	name := "test_resample"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Create a new series with values at minutes 1, 2 and 6 of the range
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for _, i := range []int{1, 2, 6} {
+		timestamp := startTime.Add(time.Duration(i) * time.Minute)
+		err = collection.Append(seriesId, timestamp, float64(i))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	nan := math.NaN()
+	tests := map[FillPolicy][]float64{
+		FillNull:     {nan, 1, 2, nan, nan, nan, 6, nan},
+		FillPrevious: {nan, 1, 2, 2, 2, 2, 6, 6},
+		FillLinear:   {nan, 1, 2, 3, 4, 5, 6, nan},
+		FillZero:     {0, 1, 2, 0, 0, 0, 6, 0},
+	}
+
+	for fill, expected := range tests {
+		data, err := collection.Resample(seriesId, startTime, startTime.Add(7*time.Minute), time.Minute, fill)
+		if err != nil {
+			t.Fatalf("Error resampling with %s fill: %s", fill, err.Error())
+		}
+
+		if len(data) != len(expected) {
+			t.Fatalf("Expected %d steps with %s fill, got %d", len(expected), fill, len(data))
+		}
+
+		for i, point := range data {
+			if !point.Timestamp().Equal(startTime.Add(time.Duration(i) * time.Minute)) {
+				t.Errorf("Unexpected timestamp for step %d with %s fill: %s", i, fill, point.Timestamp().Format(layout))
+			}
+
+			v, err := point.Float64()
+			if err != nil {
+				t.Errorf("Error reading step %d with %s fill: %s", i, fill, err.Error())
+			} else if v != expected[i] && !(math.IsNaN(v) && math.IsNaN(expected[i])) {
+				t.Errorf("Expected step %d with %s fill to be %v, got %v", i, fill, expected[i], v)
+			}
+		}
+	}
+}
+
+func TestResampleNull(t *testing.T) {
+	// a null entry at minute 2 is ignored by its step and by the fill
+	startTime := time.Now().Truncate(time.Hour)
+	timestamps := make([]time.Time, 4)
+	for i := range timestamps {
+		timestamps[i] = startTime.Add(time.Duration(i+1) * time.Minute)
+	}
+	values := []float64{1, math.NaN(), 3, math.NaN()}
+
+	nan := math.NaN()
+	tests := map[FillPolicy][]float64{
+		FillNull:     {nan, 1, nan, 3, nan},
+		FillPrevious: {nan, 1, 1, 3, 3},
+		FillLinear:   {nan, 1, 2, 3, nan},
+	}
+
+	for fill, expected := range tests {
+		data := resample(timestamps, values, startTime, startTime.Add(4*time.Minute), fixedBuckets(time.Minute), fill)
+		if len(data) != len(expected) {
+			t.Fatalf("Expected %d steps with %s fill, got %d", len(expected), fill, len(data))
+		}
+
+		for i, point := range data {
+			v, err := point.Float64()
+			if err != nil {
+				t.Errorf("Error reading step %d with %s fill: %s", i, fill, err.Error())
+			} else if v != expected[i] && !(math.IsNaN(v) && math.IsNaN(expected[i])) {
+				t.Errorf("Expected step %d with %s fill to be %v, got %v", i, fill, expected[i], v)
+			}
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package mgots
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"time"
+)
+
+// Errors
+var ErrInvalidRollup = errors.New("Rollup tiers must be a whole number of seconds")
+var ErrRollupsNotRebuilt = errors.New("The value was updated but the rollups which contain it could not be updated. Call RebuildRollups to rebuild them.")
+
+// rollup summarizes the numeric values of a series over one interval of a
+// rollup tier.
+type rollup struct {
+	Id       rollupId `bson:"_id"`
+	SeriesId interface{}
+	Time     time.Time // Start of the interval
+	summary  `bson:",inline"`
+}
+
+type rollupId struct {
+	SeriesId interface{} `bson:"s"`
+	Time     time.Time   `bson:"t"`
+}
+
+// RollupCollectionName returns the name of the companion collection in which
+// the rollups of the given tier are stored.
+func (c *NonperiodicCollection) RollupCollectionName(interval time.Duration) string {
+	return fmt.Sprintf("%s_rollup_%ds", c.Name, int64(interval/time.Second))
+}
+
+func (c *NonperiodicCollection) rollupCollection(interval time.Duration) *mgo.Collection {
+	return c.Database.C(c.RollupCollectionName(interval))
+}
+
+// prepareRollups validates the configured rollup tiers and ensures their
+// collections are indexed. Tiers are named by their number of seconds, so
+// each must be a whole number of seconds.
+func (c *NonperiodicCollection) prepareRollups() error {
+	for _, interval := range c.Rollups {
+		if interval < time.Second || interval%time.Second != 0 {
+			return ErrInvalidRollup
+		}
+
+		// indexes already ensured by this session are cached by mgo
+		err := c.rollupCollection(interval).EnsureIndexKey("seriesid", "time")
+		if err != nil {
+			return newError(err, "Error creating %s rollup index", interval)
+		}
+	}
+
+	return nil
+}
+
+// appendRollups adds a value to the current interval of every rollup tier.
+func (c *NonperiodicCollection) appendRollups(seriesId interface{}, timestamp time.Time, v float64) error {
+	for _, interval := range c.Rollups {
+		if err := c.appendRollup(seriesId, interval, timestamp, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendRollup adds a value to the interval of a rollup tier which contains
+// the given timestamp.
+func (c *NonperiodicCollection) appendRollup(seriesId interface{}, interval time.Duration, timestamp time.Time, v float64) error {
+	start := timestamp.Truncate(interval)
+	_, err := c.rollupCollection(interval).UpsertId(rollupId{seriesId, start}, bson.M{
+		"$setOnInsert": bson.M{
+			"seriesid": seriesId,
+			"time":     start,
+			"first":    v,
+		},
+		"$inc": bson.M{
+			"count": 1,
+			"sum":   v,
+		},
+		"$min": bson.M{"min": v},
+		"$max": bson.M{"max": v},
+		"$set": bson.M{"last": v},
+	})
+	if err != nil {
+		return newError(err, "Error updating %s rollup", interval)
+	}
+
+	return nil
+}
+
+// updateRollups replaces the most recent value of a series, at the given
+// timestamp, in the interval of every rollup tier which contains it. NaN is
+// given for non-numeric and null values, which are not included in rollups.
+//
+// Rollups are adjusted in place, unless the replaced value was the minimum or
+// maximum of its interval, or is replaced by a value which is not included,
+// in which case the interval is rebuilt from the values stored in the series.
+func (c *NonperiodicCollection) updateRollups(seriesId interface{}, timestamp time.Time, old float64, v float64) error {
+	for _, interval := range c.Rollups {
+		var err error
+		switch {
+		case math.IsNaN(old) && math.IsNaN(v):
+			continue
+		case math.IsNaN(old):
+			err = c.appendRollup(seriesId, interval, timestamp, v)
+		default:
+			err = c.replaceRollup(seriesId, interval, timestamp, old, v)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceRollup replaces the last value of the interval of a rollup tier
+// which contains the given timestamp.
+func (c *NonperiodicCollection) replaceRollup(seriesId interface{}, interval time.Duration, timestamp time.Time, old float64, v float64) error {
+	start := timestamp.Truncate(interval)
+	rollups := c.rollupCollection(interval)
+
+	var r rollup
+	err := rollups.FindId(rollupId{seriesId, start}).One(&r)
+	if err != nil && err != mgo.ErrNotFound {
+		return newError(err, "Error searching for %s rollup", interval)
+	}
+
+	if err == nil && !math.IsNaN(v) && r.Last == old {
+		update := bson.M{
+			"$set": bson.M{"last": v},
+			"$inc": bson.M{"sum": v - old},
+		}
+
+		switch {
+		case r.Count == 1:
+			update["$set"] = bson.M{"first": v, "last": v, "min": v, "max": v}
+		case old == r.Min && v > old, old == r.Max && v < old:
+			update = nil
+		default:
+			update["$min"] = bson.M{"min": v}
+			update["$max"] = bson.M{"max": v}
+		}
+
+		// the rollup is rebuilt if another value was added concurrently
+		if update != nil {
+			err = rollups.Update(bson.M{
+				"_id":   r.Id,
+				"count": r.Count,
+				"last":  old,
+			}, update)
+			if err != mgo.ErrNotFound {
+				if err != nil {
+					return newError(err, "Error updating %s rollup", interval)
+				}
+				return nil
+			}
+		}
+	}
+
+	return c.rebuildRollups(seriesId, interval, start, start)
+}
+
+// RebuildRollups recomputes the rollups of every tier for all intervals which
+// overlap the given time range, from the values stored in the series. Rollups
+// must be rebuilt for data appended before a tier was configured. As when
+// appending, non-numeric and null values are not included in rollups.
+func (c *NonperiodicCollection) RebuildRollups(seriesId interface{}, minTime time.Time, maxTime time.Time) error {
+	if err := c.prepareRollups(); err != nil {
+		return wrapError("RebuildRollups", seriesId, err)
+	}
+
+	for _, interval := range c.Rollups {
+		if err := c.rebuildRollups(seriesId, interval, minTime, maxTime); err != nil {
+			return wrapError("RebuildRollups", seriesId, err)
+		}
+	}
+
+	return nil
+}
+
+// rebuildRollups recomputes the rollups of a tier for all intervals which
+// overlap the given time range.
+func (c *NonperiodicCollection) rebuildRollups(seriesId interface{}, interval time.Duration, minTime time.Time, maxTime time.Time) error {
+	start := minTime.Truncate(interval)
+	end := maxTime.Truncate(interval).Add(interval)
+
+	timestamps, values, err := c.rangeNumeric(seriesId, start, end.Add(-time.Nanosecond))
+	if err != nil {
+		return err
+	}
+
+	// remove existing rollups
+	rollups := c.rollupCollection(interval)
+	_, err = rollups.RemoveAll(bson.M{
+		"seriesid": seriesId,
+		"time":     bson.M{"$gte": start, "$lt": end},
+	})
+	if err != nil {
+		return newError(err, "Error removing %s rollups", interval)
+	}
+
+	// insert rollup for each interval
+	insert := func(b *bucket) error {
+		if b == nil {
+			return nil
+		}
+
+		return rollups.Insert(rollup{
+			Id:       rollupId{seriesId, b.start},
+			SeriesId: seriesId,
+			Time:     b.start,
+			summary:  b.summary(),
+		})
+	}
+
+	var b *bucket
+	for i, timestamp := range timestamps {
+		if math.IsNaN(values[i]) {
+			continue
+		}
+
+		if b == nil || !b.start.Equal(timestamp.Truncate(interval)) {
+			if err := insert(b); err != nil {
+				return newError(err, "Error inserting %s rollup", interval)
+			}
+			b = &bucket{start: timestamp.Truncate(interval)}
+		}
+		b.add(values[i])
+	}
+
+	if err := insert(b); err != nil {
+		return newError(err, "Error inserting %s rollup", interval)
+	}
+
+	return nil
+}
+
+// rollupTier returns the coarsest rollup tier from which intervals of the
+// given duration can be aggregated, or zero if there is none.
+func (c *NonperiodicCollection) rollupTier(interval time.Duration) time.Duration {
+	var tier time.Duration
+	for _, t := range c.Rollups {
+		if t > tier && t <= interval && interval%t == 0 {
+			tier = t
+		}
+	}
+
+	return tier
+}
+
+// aggregateRollups aggregates a range from the given rollup tier. Intervals
+// of the tier which are only partially covered by the range are aggregated
+// from the raw values of the series.
+func (c *NonperiodicCollection) aggregateRollups(seriesId interface{}, minTime time.Time, maxTime time.Time, interval time.Duration, aggregator Aggregator, tier time.Duration) (DataPoints, error) {
+	a, err := newAggregation(interval, aggregator)
+	if err != nil {
+		return nil, err
+	}
+
+	// find the tier intervals which are entirely within the range
+	start := minTime.Truncate(tier)
+	if start.Before(minTime) {
+		start = start.Add(tier)
+	}
+	end := maxTime.Add(time.Nanosecond).Truncate(tier)
+
+	if !start.Before(end) {
+		points, err := c.Range(seriesId, minTime, maxTime)
+		if err != nil {
+			return nil, err
+		}
+		return aggregate(points, interval, aggregator)
+	}
+
+	// raw values before the first covered tier interval
+	timestamps, values, err := c.RangeFloat64(seriesId, minTime, start.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	for i, timestamp := range timestamps {
+		if !math.IsNaN(values[i]) {
+			a.add(timestamp, values[i])
+		}
+	}
+
+	// rollups of covered tier intervals
+	var r rollup
+	iter := c.rollupCollection(tier).Find(bson.M{
+		"seriesid": seriesId,
+		"time":     bson.M{"$gte": start, "$lt": end},
+	}).Sort("time").Iter()
+	for iter.Next(&r) {
+		a.merge(r.Time, r.bucket())
+	}
+	if err := iter.Close(); err != nil {
+		return nil, newError(err, "Error searching for %s rollups", tier)
+	}
+
+	// raw values after the last covered tier interval
+	timestamps, values, err = c.RangeFloat64(seriesId, end, maxTime)
+	if err != nil {
+		return nil, err
+	}
+	for i, timestamp := range timestamps {
+		if !math.IsNaN(values[i]) {
+			a.add(timestamp, values[i])
+		}
+	}
+
+	return a.finish(), nil
+}
+
+// rangeNumeric returns the timestamps and values of the entries of a series
+// between minTime and maxTime which can be decoded as numbers. Non-numeric
+// values are skipped rather than returning ErrNotNumeric as per RangeFloat64.
+func (c *NonperiodicCollection) rangeNumeric(seriesId interface{}, minTime time.Time, maxTime time.Time) ([]time.Time, []float64, error) {
+	pages, err := c.findPages(seriesId, minTime, maxTime, bson.M{"padding": 0})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timestamps := make([]time.Time, 0)
+	values := make([]float64, 0)
+	for _, page := range pages {
+		for i := len(page.Timestamps) - 1; i >= 0; i-- {
+			timestamp := page.Timestamps[i]
+			if timestamp.IsZero() || timestamp.Before(minTime) || timestamp.After(maxTime) {
+				continue
+			}
+
+			var v float64
+			if page.Float64s != nil {
+				v = page.Float64s[i]
+			} else if v, err = rawFloat64(page.Values[i]); err != nil {
+				continue
+			}
+
+			timestamps = append(timestamps, timestamp)
+			values = append(values, v)
+		}
+	}
+
+	return timestamps, values, nil
+}
@@ -0,0 +1,169 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestRollups(t *testing.T) {
+	database := DBConnect()
+	name := "test_rollups"
+
+	// Create a nonperiodic collection with minute and hour rollups
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	collection.Rollups = []time.Duration{time.Minute, time.Hour}
+
+	// Add a value every 10 seconds for three hours
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	entryCount := 3 * 360
+	for i := 0; i < entryCount; i++ {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i*10)*time.Second), float64(i%7))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// validate the hourly rollups
+	n, err := database.C(collection.RollupCollectionName(time.Hour)).Find(bson.M{"seriesid": seriesId}).Count()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if n != 3 {
+		t.Errorf("Expected 3 hourly rollups, got %d", n)
+	}
+
+	// aggregates from rollups should match aggregates of the raw data,
+	// including for ranges which are not aligned to the rollup tiers
+	ranges := []timeRange{
+		{startTime, startTime.Add(3 * time.Hour), 3},
+		{startTime.Add(35 * time.Second), startTime.Add(2*time.Hour + 35*time.Second), 3},
+	}
+
+	// update the most recent value, replacing a value within the range of
+	// its intervals and then their maximum
+	for _, value := range []float64{100, 2} {
+		err = collection.Update(seriesId, value)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		validateRollups(t, collection, seriesId, ranges)
+	}
+}
+
+// validateRollups compares aggregates of each range from the rollups of a
+// series with aggregates of its raw data.
+func validateRollups(t *testing.T, collection *NonperiodicCollection, seriesId interface{}, ranges []timeRange) {
+	for _, queryRange := range ranges {
+		points, err := collection.Range(seriesId, queryRange.MinTime, queryRange.MaxTime)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		for aggregator := range aggregatorNames {
+			expected, err := aggregate(points, time.Hour, aggregator)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			data, err := collection.Aggregate(seriesId, queryRange.MinTime, queryRange.MaxTime, time.Hour, aggregator)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			if len(data) != queryRange.ExpectedResults || len(expected) != queryRange.ExpectedResults {
+				t.Fatalf("Expected %d %s aggregates, got %d from rollups and %d from raw data", queryRange.ExpectedResults, aggregator, len(data), len(expected))
+			}
+
+			for i := range data {
+				v, _ := data[i].Float64()
+				e, _ := expected[i].Float64()
+				if v != e || !data[i].Timestamp().Equal(expected[i].Timestamp()) {
+					t.Errorf("Expected %s aggregate %d to be %v at %s, got %v at %s", aggregator, i, e, expected[i].Timestamp().Format(layout), v, data[i].Timestamp().Format(layout))
+				}
+			}
+		}
+	}
+}
+
+func TestRollupsNonNumeric(t *testing.T) {
+	database := DBConnect()
+	name := "test_rollups_non_numeric"
+
+	// Create a nonperiodic collection with minute rollups
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	collection.Rollups = []time.Duration{time.Minute}
+
+	// Add numeric, null and string values within one minute
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i, v := range []interface{}{1.0, nil, 3.0, "x"} {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Second), v)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// non-numeric values are excluded from rollups when updated
+	rollups := database.C(collection.RollupCollectionName(time.Minute))
+	for _, test := range []struct {
+		Value interface{}
+		Count int
+		Sum   float64
+	}{
+		{"y", 2, 4},
+		{5.0, 3, 9},
+		{nil, 2, 4},
+	} {
+		err = collection.Update(seriesId, test.Value)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		var r rollup
+		err = rollups.Find(bson.M{"seriesid": seriesId}).One(&r)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if r.Count != test.Count || r.Sum != test.Sum {
+			t.Errorf("Expected a rollup of %d values with a sum of %v after updating to %v, got %d with a sum of %v", test.Count, test.Sum, test.Value, r.Count, r.Sum)
+		}
+	}
+
+	// sub-second tiers are rejected before any value is appended
+	collection.Rollups = []time.Duration{time.Millisecond}
+	err = collection.Append(seriesId, startTime.Add(time.Minute), 5.0)
+	if !errors.Is(err, ErrInvalidRollup) || !errors.Is(err, ErrorKindInvalid) {
+		t.Errorf("Expected ErrInvalidRollup, got %v", err)
+	}
+
+	info, err := collection.Info(seriesId)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !info.LastValueTime.Equal(startTime.Add(3 * time.Second)) {
+		t.Errorf("Expected no value to be appended, got a value at %v", info.LastValueTime)
+	}
+}
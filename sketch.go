@@ -0,0 +1,145 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// SKETCH_ACCURACY is the relative accuracy of quantiles computed from page
+// sketches. E.g. a p99 of 100ms is accurate to within 1ms.
+const SKETCH_ACCURACY = 0.01
+
+var sketchGamma = (1 + SKETCH_ACCURACY) / (1 - SKETCH_ACCURACY)
+var sketchLogGamma = math.Log(sketchGamma)
+
+// Sketch is a DDSketch quantile summary of numeric values. Values are counted
+// in logarithmically sized bins, keyed by their index, so the sketch of a page
+// can be maintained by MongoDB with $inc as values are appended, and the
+// sketches of many pages can be merged to answer quantiles over long ranges.
+type Sketch struct {
+	Count    int64            `bson:"c"`           // Number of values in the sketch
+	Zero     int64            `bson:"z,omitempty"` // Number of zero values
+	Positive map[string]int64 `bson:"p,omitempty"` // Bin counts of positive values
+	Negative map[string]int64 `bson:"n,omitempty"` // Bin counts of the magnitude of negative values
+}
+
+// NewSketch returns an empty sketch.
+func NewSketch() *Sketch {
+	return &Sketch{
+		Positive: make(map[string]int64),
+		Negative: make(map[string]int64),
+	}
+}
+
+// sketchKey returns the path of the bin for the given value, relative to the
+// sketch.
+func sketchKey(v float64) string {
+	switch {
+	case v > 0:
+		return "p." + strconv.Itoa(sketchIndex(v))
+	case v < 0:
+		return "n." + strconv.Itoa(sketchIndex(-v))
+	}
+
+	return "z"
+}
+
+func sketchIndex(v float64) int {
+	return int(math.Ceil(math.Log(v) / sketchLogGamma))
+}
+
+// sketchValue returns the representative value of the bin at the given index.
+func sketchValue(index int) float64 {
+	return 2 * math.Pow(sketchGamma, float64(index)) / (1 + sketchGamma)
+}
+
+// sketchInc returns the $inc operations which add n to the count of the bin
+// for the given value, in the sketch stored at the given field.
+func sketchInc(field string, v float64, n int64) bson.M {
+	return bson.M{
+		field + ".c":               n,
+		field + "." + sketchKey(v): n,
+	}
+}
+
+// Add adds a value to the sketch.
+func (c *Sketch) Add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+
+	c.Count++
+	switch {
+	case v > 0:
+		c.Positive[strconv.Itoa(sketchIndex(v))]++
+	case v < 0:
+		c.Negative[strconv.Itoa(sketchIndex(-v))]++
+	default:
+		c.Zero++
+	}
+}
+
+// Merge adds all values in the given sketch to this sketch.
+func (c *Sketch) Merge(s *Sketch) {
+	c.Count += s.Count
+	c.Zero += s.Zero
+	for k, n := range s.Positive {
+		c.Positive[k] += n
+	}
+	for k, n := range s.Negative {
+		c.Negative[k] += n
+	}
+}
+
+// Quantile returns an estimate of the q-quantile (0 <= q <= 1) of the values
+// in the sketch, or NaN if the sketch is empty.
+func (c *Sketch) Quantile(q float64) float64 {
+	if c.Count == 0 {
+		return math.NaN()
+	}
+
+	rank := int64(q * float64(c.Count-1))
+	var seen int64
+
+	// negative values, in ascending order of value
+	for _, index := range sortedBins(c.Negative, true) {
+		seen += c.Negative[strconv.Itoa(index)]
+		if seen > rank {
+			return -sketchValue(index)
+		}
+	}
+
+	seen += c.Zero
+	if seen > rank {
+		return 0
+	}
+
+	for _, index := range sortedBins(c.Positive, false) {
+		seen += c.Positive[strconv.Itoa(index)]
+		if seen > rank {
+			return sketchValue(index)
+		}
+	}
+
+	return math.NaN()
+}
+
+// sortedBins returns the indexes of the non-empty bins in the given map.
+func sortedBins(bins map[string]int64, reverse bool) []int {
+	indexes := make([]int, 0, len(bins))
+	for k, n := range bins {
+		if index, err := strconv.Atoi(k); err == nil && n > 0 {
+			indexes = append(indexes, index)
+		}
+	}
+
+	if reverse {
+		sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+	} else {
+		sort.Ints(indexes)
+	}
+
+	return indexes
+}
@@ -0,0 +1,174 @@
+package mgots
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Predicate is a condition on the numeric value of a data point.
+type Predicate func(v float64) bool
+
+// Above returns a Predicate which holds for values greater than threshold.
+func Above(threshold float64) Predicate {
+	return func(v float64) bool { return v > threshold }
+}
+
+// Below returns a Predicate which holds for values less than threshold.
+func Below(threshold float64) Predicate {
+	return func(v float64) bool { return v < threshold }
+}
+
+// Interval is a span of time.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (c Interval) Duration() time.Duration {
+	return c.End.Sub(c.Start)
+}
+
+// IntervalSet is the set of intervals in which a Predicate held.
+type IntervalSet struct {
+	Intervals []Interval
+	Count     int           // number of intervals
+	Duration  time.Duration // total duration of all intervals
+}
+
+// StateInterval is a span of time in which a series had the same value.
+type StateInterval struct {
+	Interval
+	Value string
+}
+
+// StateTimeline describes the values of a series over a range.
+type StateTimeline struct {
+	Intervals []StateInterval
+	Counts    map[string]int           // number of intervals of each value
+	Durations map[string]time.Duration // total duration of each value
+}
+
+// IntervalsWhere returns the intervals between minTime and maxTime in which the
+// given predicate held for the value of a series.
+//
+// The value of each data point is considered to hold until the next data
+// point, and the value of the last data point until maxTime. The value at
+// minTime is that of the last data point at or before minTime. Null and
+// non-numeric values do not satisfy the predicate.
+func (c *NonperiodicCollection) IntervalsWhere(seriesId interface{}, minTime time.Time, maxTime time.Time, predicate Predicate) (*IntervalSet, error) {
+	points, err := c.statePoints(seriesId, minTime, maxTime)
+	if err != nil {
+		return nil, wrapError("IntervalsWhere", seriesId, err)
+	}
+
+	set := &IntervalSet{Intervals: make([]Interval, 0)}
+	for _, state := range states(points, minTime, maxTime, func(point DataPoint) string {
+		v, err := point.Float64()
+		if err != nil || math.IsNaN(v) {
+			return "false"
+		}
+		return fmt.Sprint(predicate(v))
+	}) {
+		if state.Value == "true" {
+			set.Intervals = append(set.Intervals, state.Interval)
+			set.Count++
+			set.Duration += state.Duration()
+		}
+	}
+
+	return set, nil
+}
+
+// StateTimeline returns the intervals between minTime and maxTime in which a
+// series of discrete values, such as strings or enumerations, had the same
+// value, with the total duration of each value. Values are compared as
+// formatted by fmt.Sprint, with null values formatted as "null". Values hold
+// as per IntervalsWhere.
+func (c *NonperiodicCollection) StateTimeline(seriesId interface{}, minTime time.Time, maxTime time.Time) (*StateTimeline, error) {
+	points, err := c.statePoints(seriesId, minTime, maxTime)
+	if err != nil {
+		return nil, wrapError("StateTimeline", seriesId, err)
+	}
+
+	var decodeErr error
+	timeline := &StateTimeline{
+		Intervals: states(points, minTime, maxTime, func(point DataPoint) string {
+			var v interface{}
+			if err := point.GetValue(&v); err != nil {
+				decodeErr = err
+			}
+
+			if v == nil {
+				return "null"
+			}
+			return fmt.Sprint(v)
+		}),
+		Counts:    make(map[string]int),
+		Durations: make(map[string]time.Duration),
+	}
+
+	if decodeErr != nil {
+		return nil, wrapError("StateTimeline", seriesId, newError(decodeErr, "Error decoding value"))
+	}
+
+	for _, state := range timeline.Intervals {
+		timeline.Counts[state.Value]++
+		timeline.Durations[state.Value] += state.Duration()
+	}
+
+	return timeline, nil
+}
+
+// statePoints returns the data points of a series between minTime and
+// maxTime, preceded by the last data point before minTime, if any.
+func (c *NonperiodicCollection) statePoints(seriesId interface{}, minTime time.Time, maxTime time.Time) (DataPoints, error) {
+	if maxTime.Before(minTime) {
+		return DataPoints{}, nil
+	}
+
+	points, err := c.Range(seriesId, minTime, maxTime)
+	if err != nil {
+		return nil, err
+	}
+
+	prior, err := c.before(seriesId, minTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if prior != nil && prior.Timestamp().Before(minTime) {
+		points = append(DataPoints{prior}, points...)
+	}
+
+	return points, nil
+}
+
+// states returns the intervals between minTime and maxTime in which the
+// given key of chronologically ordered data points was the same.
+func states(points DataPoints, minTime time.Time, maxTime time.Time, key func(point DataPoint) string) []StateInterval {
+	results := make([]StateInterval, 0)
+	for _, point := range points {
+		start := point.Timestamp()
+		if start.Before(minTime) {
+			start = minTime
+		}
+
+		k := key(point)
+		n := len(results)
+		if n > 0 {
+			if results[n-1].Value == k {
+				continue
+			}
+			results[n-1].End = start
+		}
+
+		results = append(results, StateInterval{Interval{Start: start}, k})
+	}
+
+	if n := len(results); n > 0 {
+		results[n-1].End = maxTime
+	}
+
+	return results
+}
@@ -0,0 +1,121 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIntervalsWhere(t *testing.T) {
+	database := DBConnect()
+	name := "test_intervals_where"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add a value every minute
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i, v := range []float64{50, 95, 97, 80, 92, 40} {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Minute), v)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// The range starts while the value is above the threshold
+	minute := func(m float64) time.Time {
+		return startTime.Add(time.Duration(m * float64(time.Minute)))
+	}
+
+	set, err := collection.IntervalsWhere(seriesId, minute(1.5), minute(10), Above(90))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []Interval{
+		{minute(1.5), minute(3)},
+		{minute(4), minute(5)},
+	}
+
+	if !reflect.DeepEqual(set.Intervals, expected) {
+		t.Errorf("Expected intervals %v, got %v", expected, set.Intervals)
+	}
+
+	if set.Count != 2 || set.Duration != 150*time.Second {
+		t.Errorf("Expected 2 intervals totalling 2m30s, got %d totalling %v", set.Count, set.Duration)
+	}
+
+	// The last value holds until the end of the range
+	set, err = collection.IntervalsWhere(seriesId, minute(0), minute(10), Below(60))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if set.Count != 2 || set.Duration != 6*time.Minute {
+		t.Errorf("Expected 2 intervals totalling 6m, got %d totalling %v", set.Count, set.Duration)
+	}
+}
+
+func TestStateTimeline(t *testing.T) {
+	database := DBConnect()
+	name := "test_state_timeline"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add a state every minute
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i, v := range []string{"ok", "ok", "warn", "crit", "ok"} {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Minute), v)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	timeline, err := collection.StateTimeline(seriesId, startTime, startTime.Add(10*time.Minute))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	minute := func(m int) time.Time {
+		return startTime.Add(time.Duration(m) * time.Minute)
+	}
+
+	expected := []StateInterval{
+		{Interval{minute(0), minute(2)}, "ok"},
+		{Interval{minute(2), minute(3)}, "warn"},
+		{Interval{minute(3), minute(4)}, "crit"},
+		{Interval{minute(4), minute(10)}, "ok"},
+	}
+
+	if !reflect.DeepEqual(timeline.Intervals, expected) {
+		t.Errorf("Expected states %v, got %v", expected, timeline.Intervals)
+	}
+
+	if timeline.Counts["ok"] != 2 || timeline.Durations["ok"] != 8*time.Minute {
+		t.Errorf("Expected 2 ok intervals totalling 8m, got %d totalling %v", timeline.Counts["ok"], timeline.Durations["ok"])
+	}
+
+	if timeline.Durations["crit"] != time.Minute {
+		t.Errorf("Expected crit for 1m, got %v", timeline.Durations["crit"])
+	}
+}
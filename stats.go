@@ -0,0 +1,167 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"time"
+)
+
+// Errors
+var ErrStatsUnavailable = errors.New("Statistics are incomplete for series created before statistics were introduced or whose minimum or maximum value was updated. Call RecomputeStats to compute them.")
+
+// SeriesStats describes all entries appended to a series over its lifetime.
+type SeriesStats struct {
+	Count     int       // Number of entries in the series
+	FirstTime time.Time // Timestamp of the first entry
+	LastTime  time.Time // Timestamp of the most recent entry
+	Numeric   int       // Number of numeric entries
+	Min       float64   // Minimum numeric value
+	Max       float64   // Maximum numeric value
+	Sum       float64   // Sum of all numeric values
+}
+
+// Mean returns the mean of all numeric values, or NaN if there are none.
+func (c *SeriesStats) Mean() float64 {
+	if c.Numeric == 0 {
+		return math.NaN()
+	}
+
+	return c.Sum / float64(c.Numeric)
+}
+
+// seriesStats is the stored form of SeriesStats on a series cursor.
+type seriesStats struct {
+	Complete  bool      // True if the statistics describe every entry in the series
+	Count     int       // Number of entries in the series
+	FirstTime time.Time `bson:",omitempty"` // Timestamp of the first entry
+	Numeric   int       // Number of numeric entries
+	Min       float64   `bson:",omitempty"` // Minimum numeric value, set with the first numeric entry
+	Max       float64   `bson:",omitempty"` // Maximum numeric value, set with the first numeric entry
+	Sum       float64   // Sum of all numeric values
+}
+
+// statsChange adds the operations which update the statistics of a series
+// cursor for an appended entry to the given change.
+func statsChange(change bson.M, timestamp time.Time, f float64, numeric bool) {
+	inc := change["$inc"].(bson.M)
+	inc["stats.count"] = 1
+	change["$min"] = bson.M{"stats.firsttime": timestamp}
+
+	if numeric && !math.IsNaN(f) {
+		inc["stats.numeric"] = 1
+		inc["stats.sum"] = f
+		change["$min"].(bson.M)["stats.min"] = f
+		change["$max"] = bson.M{"stats.max": f}
+	}
+}
+
+// Stats returns the lifetime statistics of a series, maintained as entries
+// are appended.
+func (c *NonperiodicCollection) Stats(seriesId interface{}) (*SeriesStats, error) {
+	var cursor seriesCursor
+	err := c.DBCursorCollection.FindId(seriesId).Select(bson.M{"lastvalue": 0}).One(&cursor)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, wrapError("Stats", seriesId, ErrSeriesNotFound)
+		}
+
+		return nil, wrapError("Stats", seriesId, newError(err, "Error searching for time series"))
+	}
+
+	if cursor.Stats == nil || !cursor.Stats.Complete {
+		return nil, wrapError("Stats", seriesId, ErrStatsUnavailable)
+	}
+
+	stats := &SeriesStats{
+		Count:     cursor.Stats.Count,
+		FirstTime: cursor.Stats.FirstTime,
+		Numeric:   cursor.Stats.Numeric,
+		Min:       cursor.Stats.Min,
+		Max:       cursor.Stats.Max,
+		Sum:       cursor.Stats.Sum,
+	}
+
+	if !cursor.LastValueTime.Equal(timeZero) {
+		stats.LastTime = cursor.LastValueTime
+	}
+
+	return stats, nil
+}
+
+// RecomputeStats computes the lifetime statistics of a series from all of
+// its pages and stores them on the series cursor. It must be called for
+// series created before statistics were introduced, or after Update replaced
+// the minimum or maximum value, while no values are being appended to the
+// series.
+func (c *NonperiodicCollection) RecomputeStats(seriesId interface{}) error {
+	var b bucket
+	count := 0
+	var firstTime time.Time
+
+	var page dataPage
+	iter := c.DBCollection.Find(bson.M{"seriesid": seriesId}).Select(bson.M{"padding": 0, "sketch": 0}).Sort("starttime").Iter()
+	for iter.Next(&page) {
+		for i := len(page.Timestamps) - 1; i >= 0; i-- {
+			timestamp := page.Timestamps[i]
+			if timestamp.IsZero() {
+				continue
+			}
+
+			if count == 0 {
+				firstTime = timestamp
+			}
+			count++
+
+			var v float64
+			var err error
+			if page.Float64s != nil {
+				v = page.Float64s[i]
+			} else if v, err = rawFloat64(page.Values[i]); err != nil {
+				continue
+			}
+
+			if !math.IsNaN(v) {
+				b.add(v)
+			}
+		}
+
+		page = dataPage{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return wrapError("RecomputeStats", seriesId, newError(err, "Error searching for time series pages"))
+	}
+
+	// minimum and maximum are only stored if there are numeric values, so
+	// they are set by $min and $max on the next numeric entry
+	stats := bson.M{
+		"complete": true,
+		"count":    count,
+		"numeric":  b.count,
+		"sum":      b.sum,
+	}
+
+	if count > 0 {
+		stats["firsttime"] = firstTime
+	}
+
+	if b.count > 0 {
+		stats["min"] = b.min
+		stats["max"] = b.max
+	}
+
+	err := c.DBCursorCollection.UpdateId(seriesId, bson.M{
+		"$set": bson.M{"stats": stats},
+	})
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return wrapError("RecomputeStats", seriesId, ErrSeriesNotFound)
+		}
+
+		return wrapError("RecomputeStats", seriesId, newError(err, "Error updating series statistics"))
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	database := DBConnect()
+	name := "test_stats"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Create a new series with values 1 to 1000
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	entryCount := 1000
+	for i := 1; i <= entryCount; i++ {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i)*time.Minute), float64(i))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	lastTime := startTime.Add(time.Duration(entryCount) * time.Minute)
+	expected := SeriesStats{
+		Count:     entryCount,
+		FirstTime: startTime.Add(time.Minute),
+		LastTime:  lastTime,
+		Numeric:   entryCount,
+		Min:       1,
+		Max:       1000,
+		Sum:       500500,
+	}
+
+	validate := func(name string) {
+		stats, err := collection.Stats(seriesId)
+		if err != nil {
+			t.Fatalf("Error getting %s statistics: %s", name, err.Error())
+		}
+
+		if *stats != expected {
+			t.Errorf("Expected %s statistics %+v, got %+v", name, expected, *stats)
+		}
+	}
+	validate("appended")
+
+	// Replace the maximum value
+	err = collection.Update(seriesId, 10)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	_, err = collection.Stats(seriesId)
+	if !errors.Is(err, ErrStatsUnavailable) {
+		t.Errorf("Expected statistics to be unavailable after replacing the maximum, got %v", err)
+	}
+
+	err = collection.RecomputeStats(seriesId)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected.Max = 999
+	expected.Sum = 500500 - 1000 + 10
+	validate("updated")
+
+	// Replace a value which is neither the minimum nor the maximum
+	err = collection.Update(seriesId, 20)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected.Sum += 10
+	validate("updated again")
+
+	// Simulate a series created before statistics were introduced
+	err = database.C(name+cursorSuffix).UpdateId(seriesId, bson.M{"$unset": bson.M{"stats": ""}})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	err = collection.Append(seriesId, lastTime.Add(time.Minute), float64(-5))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	_, err = collection.Stats(seriesId)
+	if !errors.Is(err, ErrStatsUnavailable) {
+		t.Errorf("Expected incomplete statistics to be unavailable, got %v", err)
+	}
+
+	err = collection.RecomputeStats(seriesId)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected.Count++
+	expected.Numeric++
+	expected.LastTime = lastTime.Add(time.Minute)
+	expected.Min = -5
+	expected.Sum -= 5
+	validate("recomputed")
+}
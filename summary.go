@@ -0,0 +1,131 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"math"
+	"time"
+)
+
+// summarized returns true if all entries of the page are described by its
+// summary, so it can be aggregated without decoding its values.
+func (c *dataPage) summarized() bool {
+	return c.Summary != nil && c.Summary.Count > 0 && !c.Mixed
+}
+
+// summarizePage recomputes the summary of a page from its values.
+func (c *NonperiodicCollection) summarizePage(pageId interface{}) error {
+	var page dataPage
+	err := c.DBCollection.FindId(pageId).Select(bson.M{"padding": 0, "sketch": 0}).One(&page)
+	if err != nil {
+		return newError(err, "Error fetching page to summarize")
+	}
+
+	var b bucket
+	mixed := false
+	for i := len(page.Timestamps) - 1; i >= 0; i-- {
+		if page.Timestamps[i].IsZero() {
+			continue
+		}
+
+		var v float64
+		if page.Float64s != nil {
+			v = page.Float64s[i]
+		} else if v, err = rawFloat64(page.Values[i]); err != nil {
+			mixed = true
+			continue
+		}
+
+		if math.IsNaN(v) {
+			mixed = true
+			continue
+		}
+
+		b.add(v)
+	}
+
+	change := bson.M{
+		"$set": bson.M{
+			"summary": b.summary(),
+			"mixed":   mixed,
+		},
+	}
+	if b.count == 0 {
+		change = bson.M{
+			"$set":   bson.M{"mixed": mixed},
+			"$unset": bson.M{"summary": ""},
+		}
+	}
+
+	if err := c.DBCollection.UpdateId(pageId, change); err != nil {
+		return newError(err, "Error updating page summary")
+	}
+
+	return nil
+}
+
+// aggregatePages aggregates a range from the summaries in page headers. Only
+// the values of pages which span the boundary of an interval or the range, or
+// which have no summary, are decoded.
+func (c *NonperiodicCollection) aggregatePages(seriesId interface{}, minTime time.Time, maxTime time.Time, b bucketing, aggregator Aggregator) (DataPoints, error) {
+	a, err := newBucketAggregation(b, aggregator)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch headers of all pages in the range
+	pages, err := c.findPages(seriesId, minTime, maxTime, bson.M{"timestamps": 0, "values": 0, "float64s": 0, "padding": 0, "sketch": 0})
+	if err != nil {
+		return nil, err
+	}
+
+	// a page is covered if all of its entries fall within the range and a
+	// single bucket
+	covered := func(page *dataPage) bool {
+		return page.summarized() &&
+			!page.StartTime.Before(minTime) &&
+			!page.EndTime.After(maxTime) &&
+			b.start(page.StartTime).Equal(b.start(page.EndTime))
+	}
+
+	// fetch the values of all other pages
+	decode := make([]interface{}, 0)
+	for i := range pages {
+		if !covered(&pages[i]) {
+			decode = append(decode, pages[i].PageId)
+		}
+	}
+
+	boundaryPages := make(map[interface{}]dataPage)
+	if len(decode) > 0 {
+		var page dataPage
+		iter := c.DBCollection.Find(bson.M{"_id": bson.M{"$in": decode}}).Select(bson.M{"padding": 0, "sketch": 0}).Iter()
+		for iter.Next(&page) {
+			boundaryPages[page.PageId] = page
+			page = dataPage{}
+		}
+		if err := iter.Close(); err != nil {
+			return nil, newError(err, "Error fetching boundary pages")
+		}
+	}
+
+	// aggregate pages in chronological order
+	for i := range pages {
+		if covered(&pages[i]) {
+			a.merge(pages[i].StartTime, pages[i].Summary.bucket())
+			continue
+		}
+
+		timestamps, values, err := pagesFloat64([]dataPage{boundaryPages[pages[i].PageId]})
+		if err != nil {
+			return nil, err
+		}
+
+		for j, timestamp := range timestamps {
+			if !timestamp.Before(minTime) && !timestamp.After(maxTime) {
+				a.add(timestamp, values[j])
+			}
+		}
+	}
+
+	return a.finish(), nil
+}
@@ -0,0 +1,102 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestPageSummaries(t *testing.T) {
+	database := DBConnect()
+	name := "test_page_summaries"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Add a value every 10 seconds for three hours
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	entryCount := 3 * 360
+	for i := 0; i < entryCount; i++ {
+		err = collection.Append(seriesId, startTime.Add(time.Duration(i*10)*time.Second), float64(i%11))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// update the most recent value
+	err = collection.Update(seriesId, 100)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// validate the summary of each page
+	var pages []dataPage
+	err = database.C(name).Find(bson.M{"seriesid": seriesId}).Sort("starttime").All(&pages)
+	if err != nil {
+		t.Fatalf("Failed to fetch pages")
+	}
+
+	for i, page := range pages {
+		_, values, err := pagesFloat64([]dataPage{page})
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		var expected bucket
+		for _, v := range values {
+			expected.add(v)
+		}
+
+		if page.Summary == nil {
+			t.Errorf("Page %d has no summary", i)
+		} else if *page.Summary != expected.summary() {
+			t.Errorf("Expected summary of page %d to be %+v, got %+v", i, expected.summary(), *page.Summary)
+		}
+	}
+
+	// aggregates from page summaries should match aggregates of the raw data
+	ranges := []timeRange{
+		{startTime, startTime.Add(3 * time.Hour), 3},
+		{startTime.Add(35 * time.Second), startTime.Add(2*time.Hour + 35*time.Second), 3},
+	}
+
+	for _, queryRange := range ranges {
+		points, err := collection.Range(seriesId, queryRange.MinTime, queryRange.MaxTime)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		for aggregator := range aggregatorNames {
+			expected, err := aggregate(points, time.Hour, aggregator)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			data, err := collection.Aggregate(seriesId, queryRange.MinTime, queryRange.MaxTime, time.Hour, aggregator)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+
+			if len(data) != queryRange.ExpectedResults || len(expected) != queryRange.ExpectedResults {
+				t.Fatalf("Expected %d %s aggregates, got %d from page summaries and %d from raw data", queryRange.ExpectedResults, aggregator, len(data), len(expected))
+			}
+
+			for i := range data {
+				v, _ := data[i].Float64()
+				e, _ := expected[i].Float64()
+				if v != e || !data[i].Timestamp().Equal(expected[i].Timestamp()) {
+					t.Errorf("Expected %s aggregate %d to be %v at %s, got %v at %s", aggregator, i, e, expected[i].Timestamp().Format(layout), v, data[i].Timestamp().Format(layout))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"strings"
+)
+
+// Errors
+var ErrInvalidTag = errors.New("Tag names must not be empty, begin with '$' or contain '.'")
+
+// validateTags returns ErrInvalidTag if any tag name cannot be stored as a
+// MongoDB field name.
+func validateTags(tags map[string]string) error {
+	for name := range tags {
+		if name == "" || strings.HasPrefix(name, "$") || strings.Contains(name, ".") {
+			return ErrInvalidTag
+		}
+	}
+
+	return nil
+}
+
+// SetTags replaces the tags of a series. Tags are name/value pairs used to
+// find series with FindSeries and to group series in expressions.
+func (c *NonperiodicCollection) SetTags(seriesId interface{}, tags map[string]string) error {
+	if err := validateTags(tags); err != nil {
+		return wrapError("SetTags", seriesId, err)
+	}
+
+	change := bson.M{"$set": bson.M{"tags": tags}}
+	if len(tags) == 0 {
+		change = bson.M{"$unset": bson.M{"tags": ""}}
+	}
+
+	err := c.DBCursorCollection.UpdateId(seriesId, change)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return wrapError("SetTags", seriesId, ErrSeriesNotFound)
+		}
+
+		return wrapError("SetTags", seriesId, newError(err, "Error updating series tags"))
+	}
+
+	return nil
+}
+
+// FindSeries returns the ID of every series which has all of the given tags.
+// All series are returned if no tags are given.
+func (c *NonperiodicCollection) FindSeries(tags map[string]string) ([]interface{}, error) {
+	if err := validateTags(tags); err != nil {
+		return nil, wrapError("FindSeries", nil, err)
+	}
+
+	query := bson.M{}
+	for name, value := range tags {
+		query["tags."+name] = value
+	}
+
+	var cursors []seriesCursor
+	err := c.DBCursorCollection.Find(query).Select(bson.M{"_id": 1}).Sort("_id").All(&cursors)
+	if err != nil {
+		return nil, wrapError("FindSeries", nil, newError(err, "Error searching for series cursors"))
+	}
+
+	series := make([]interface{}, len(cursors))
+	for i, cursor := range cursors {
+		series[i] = cursor.SeriesId
+	}
+
+	return series, nil
+}
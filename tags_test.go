@@ -0,0 +1,82 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTags(t *testing.T) {
+	database := DBConnect()
+	name := "test_tags"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Create series tagged by host and metric
+	tags := []map[string]string{
+		{"host": "a", "metric": "cpu"},
+		{"host": "b", "metric": "cpu"},
+		{"host": "a", "metric": "memory"},
+	}
+
+	ids := make([]interface{}, len(tags))
+	for i := range tags {
+		ids[i] = bson.NewObjectId()
+		err = collection.CreateSeries(ids[i], time.Now())
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		err = collection.SetTags(ids[i], tags[i])
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	info, err := collection.Info(ids[0])
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !reflect.DeepEqual(info.Tags, tags[0]) {
+		t.Errorf("Expected tags %v, got %v", tags[0], info.Tags)
+	}
+
+	// Find series by tags
+	searches := []struct {
+		Tags     map[string]string
+		Expected []interface{}
+	}{
+		{map[string]string{"metric": "cpu"}, []interface{}{ids[0], ids[1]}},
+		{map[string]string{"host": "a"}, []interface{}{ids[0], ids[2]}},
+		{map[string]string{"host": "a", "metric": "memory"}, []interface{}{ids[2]}},
+		{map[string]string{"host": "c"}, []interface{}{}},
+		{nil, ids},
+	}
+
+	for _, search := range searches {
+		series, err := collection.FindSeries(search.Tags)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if !reflect.DeepEqual(series, search.Expected) {
+			t.Errorf("Expected series %v for tags %v, got %v", search.Expected, search.Tags, series)
+		}
+	}
+
+	// Invalid and missing series
+	if err := collection.SetTags(ids[0], map[string]string{"a.b": "c"}); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("Expected ErrInvalidTag, got %v", err)
+	}
+
+	if err := collection.SetTags(bson.NewObjectId(), tags[0]); !errors.Is(err, ErrSeriesNotFound) {
+		t.Errorf("Expected ErrSeriesNotFound, got %v", err)
+	}
+}
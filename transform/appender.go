@@ -0,0 +1,59 @@
+package transform
+
+import (
+	"github.com/cavaliercoder/mgots"
+	"time"
+)
+
+// Appender appends values to a series in a Collection and appends the
+// transformed value of each to a derived series, so smoothed series are
+// maintained during ingestion rather than computed after every Range call.
+//
+// The state of the Transformer is held in memory. Appender is not safe for
+// concurrent use.
+type Appender struct {
+	Collection      mgots.Collection
+	SeriesId        interface{} // ID of the series which receives raw values
+	DerivedSeriesId interface{} // ID of the series which receives transformed values
+	Transformer     Transformer
+}
+
+// NewAppender returns an Appender which appends values to the given series
+// and their transformed values to the derived series. Both series must
+// already exist.
+func NewAppender(collection mgots.Collection, seriesId interface{}, derivedSeriesId interface{}, t Transformer) *Appender {
+	return &Appender{
+		Collection:      collection,
+		SeriesId:        seriesId,
+		DerivedSeriesId: derivedSeriesId,
+		Transformer:     t,
+	}
+}
+
+// Append appends a value to the series and the transformed value to the
+// derived series. The Transformer only consumes values which were
+// successfully appended.
+func (c *Appender) Append(timestamp time.Time, value float64) error {
+	if err := c.Collection.Append(c.SeriesId, timestamp, value); err != nil {
+		return err
+	}
+
+	return c.Collection.Append(c.DerivedSeriesId, timestamp, c.Transformer.Next(timestamp, value))
+}
+
+// Prime feeds the Transformer with the data points of the series between
+// minTime and maxTime, without appending anything, so an Appender created
+// after a restart continues from the same state.
+func (c *Appender) Prime(minTime time.Time, maxTime time.Time) error {
+	timestamps, values, err := c.Collection.RangeFloat64(c.SeriesId, minTime, maxTime)
+	if err != nil {
+		return err
+	}
+
+	c.Transformer.Reset()
+	for i, timestamp := range timestamps {
+		c.Transformer.Next(timestamp, values[i])
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+// Package transform implements smoothing functions, such as moving averages
+// and rolling extremes, over the numeric values of mgots time series.
+//
+// Each function is implemented as a Transformer which consumes one data point
+// at a time, so the same transformation can be applied to the result of a
+// Range query with Apply, or to values as they are ingested with an Appender.
+package transform
+
+import (
+	"errors"
+	"github.com/cavaliercoder/mgots"
+	"math"
+	"time"
+)
+
+// Errors
+var ErrInvalidWindow = errors.New("Window size must be greater than zero")
+
+// Transformer computes a derived value for each value in a series. Values
+// must be given in chronological order.
+type Transformer interface {
+	// Next consumes a value and returns the transformed value at the same
+	// timestamp. NaN (null) values are not consumed and are returned as NaN.
+	Next(timestamp time.Time, value float64) float64
+
+	// Reset discards all values consumed by the Transformer.
+	Reset()
+}
+
+// Apply returns the transformed value of every data point. The Transformer is
+// reset before the first data point.
+func Apply(points mgots.DataPoints, t Transformer) (mgots.DataPoints, error) {
+	t.Reset()
+	results := make(mgots.DataPoints, len(points))
+	for i, point := range points {
+		v, err := point.Float64()
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = mgots.NewFloat64DataPoint(point.Timestamp(), t.Next(point.Timestamp(), v))
+	}
+
+	return results, nil
+}
+
+// sample is a single value in a window.
+type sample struct {
+	timestamp time.Time
+	value     float64
+}
+
+// window retains the most recent values consumed by a Transformer, limited
+// either by count or by age.
+type window struct {
+	size     int           // maximum number of values, if greater than zero
+	duration time.Duration // maximum age of values, if greater than zero
+	samples  []sample
+}
+
+// countWindow returns a window of the last n values.
+func countWindow(n int) (window, error) {
+	if n < 1 {
+		return window{}, ErrInvalidWindow
+	}
+
+	return window{size: n}, nil
+}
+
+// timeWindow returns a window of the values within the given duration of the
+// most recent value.
+func timeWindow(d time.Duration) (window, error) {
+	if d <= 0 {
+		return window{}, ErrInvalidWindow
+	}
+
+	return window{duration: d}, nil
+}
+
+// push adds a value to the window and returns the values which were evicted.
+func (c *window) push(timestamp time.Time, value float64) []sample {
+	c.samples = append(c.samples, sample{timestamp, value})
+
+	n := 0
+	if c.size > 0 && len(c.samples) > c.size {
+		n = len(c.samples) - c.size
+	}
+
+	if c.duration > 0 {
+		for n < len(c.samples) && !c.samples[n].timestamp.After(timestamp.Add(-c.duration)) {
+			n++
+		}
+	}
+
+	evicted := c.samples[:n]
+	c.samples = c.samples[n:]
+	return evicted
+}
+
+func (c *window) reset() {
+	c.samples = nil
+}
+
+// MovingAverage is the arithmetic mean of the values in a window.
+type MovingAverage struct {
+	window window
+	sum    float64
+}
+
+// NewMovingAverage returns the moving average of the last n values.
+// ErrInvalidWindow is returned if n is less than one.
+func NewMovingAverage(n int) (*MovingAverage, error) {
+	w, err := countWindow(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MovingAverage{window: w}, nil
+}
+
+// NewTimeMovingAverage returns the moving average of all values within the
+// given duration of the most recent value. ErrInvalidWindow is returned if
+// the duration is not positive.
+func NewTimeMovingAverage(d time.Duration) (*MovingAverage, error) {
+	w, err := timeWindow(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MovingAverage{window: w}, nil
+}
+
+func (c *MovingAverage) Next(timestamp time.Time, value float64) float64 {
+	if math.IsNaN(value) {
+		return value
+	}
+
+	c.sum += value
+	for _, s := range c.window.push(timestamp, value) {
+		c.sum -= s.value
+	}
+
+	return c.sum / float64(len(c.window.samples))
+}
+
+func (c *MovingAverage) Reset() {
+	c.window.reset()
+	c.sum = 0
+}
+
+// EWMA is an exponentially weighted moving average.
+type EWMA struct {
+	Alpha float64 // weight of each new value, between 0 and 1
+
+	value   float64
+	started bool
+}
+
+// NewEWMA returns an exponentially weighted moving average in which each new
+// value has the given weight.
+func NewEWMA(alpha float64) *EWMA {
+	return &EWMA{Alpha: alpha}
+}
+
+// NewEWMASpan returns an exponentially weighted moving average with a weight
+// equivalent to a moving average of n values.
+func NewEWMASpan(n int) *EWMA {
+	return NewEWMA(2 / (float64(n) + 1))
+}
+
+func (c *EWMA) Next(timestamp time.Time, value float64) float64 {
+	if math.IsNaN(value) {
+		return value
+	}
+
+	if !c.started {
+		c.value = value
+		c.started = true
+	} else {
+		c.value = c.Alpha*value + (1-c.Alpha)*c.value
+	}
+
+	return c.value
+}
+
+func (c *EWMA) Reset() {
+	c.value = 0
+	c.started = false
+}
+
+// Rolling is the minimum or maximum of the values in a window.
+type Rolling struct {
+	window window
+	less   func(a, b float64) bool
+
+	// monotonic queue of candidate extremes, in the order they were consumed
+	queue    []rollingCandidate
+	consumed int
+	evicted  int
+}
+
+type rollingCandidate struct {
+	seq   int
+	value float64
+}
+
+// NewRollingMin returns the minimum of the last n values. ErrInvalidWindow
+// is returned if n is less than one.
+func NewRollingMin(n int) (*Rolling, error) {
+	w, err := countWindow(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rolling{window: w, less: lt}, nil
+}
+
+// NewTimeRollingMin returns the minimum of all values within the given
+// duration of the most recent value. ErrInvalidWindow is returned if the
+// duration is not positive.
+func NewTimeRollingMin(d time.Duration) (*Rolling, error) {
+	w, err := timeWindow(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rolling{window: w, less: lt}, nil
+}
+
+// NewRollingMax returns the maximum of the last n values. ErrInvalidWindow
+// is returned if n is less than one.
+func NewRollingMax(n int) (*Rolling, error) {
+	w, err := countWindow(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rolling{window: w, less: gt}, nil
+}
+
+// NewTimeRollingMax returns the maximum of all values within the given
+// duration of the most recent value. ErrInvalidWindow is returned if the
+// duration is not positive.
+func NewTimeRollingMax(d time.Duration) (*Rolling, error) {
+	w, err := timeWindow(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rolling{window: w, less: gt}, nil
+}
+
+func (c *Rolling) Next(timestamp time.Time, value float64) float64 {
+	if math.IsNaN(value) {
+		return value
+	}
+
+	// discard candidates which can no longer be the extreme
+	for len(c.queue) > 0 && !c.less(c.queue[len(c.queue)-1].value, value) {
+		c.queue = c.queue[:len(c.queue)-1]
+	}
+	c.queue = append(c.queue, rollingCandidate{c.consumed, value})
+	c.consumed++
+
+	// discard candidates which have left the window
+	c.evicted += len(c.window.push(timestamp, value))
+	for c.queue[0].seq < c.evicted {
+		c.queue = c.queue[1:]
+	}
+
+	return c.queue[0].value
+}
+
+func (c *Rolling) Reset() {
+	c.window.reset()
+	c.queue = nil
+	c.consumed = 0
+	c.evicted = 0
+}
+
+func lt(a, b float64) bool {
+	return a < b
+}
+
+func gt(a, b float64) bool {
+	return a > b
+}
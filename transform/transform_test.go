@@ -0,0 +1,116 @@
+package transform
+
+import (
+	"github.com/cavaliercoder/mgots"
+	"math"
+	"testing"
+	"time"
+)
+
+type transformTest struct {
+	Name        string
+	Transformer Transformer
+	Expected    []float64
+}
+
+var startTime = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testPoints returns one data point per minute with the given values.
+func testPoints(values ...float64) mgots.DataPoints {
+	points := make(mgots.DataPoints, len(values))
+	for i, v := range values {
+		points[i] = mgots.NewFloat64DataPoint(startTime.Add(time.Duration(i)*time.Minute), v)
+	}
+
+	return points
+}
+
+func TestTransformers(t *testing.T) {
+	points := testPoints(4, 2, 6, 8, 1, 3)
+
+	tests := []transformTest{
+		{"MovingAverage", mustTransformer(NewMovingAverage(2)), []float64{4, 3, 4, 7, 4.5, 2}},
+		{"TimeMovingAverage", mustTransformer(NewTimeMovingAverage(3 * time.Minute)), []float64{4, 3, 4, 16.0 / 3, 5, 4}},
+		{"EWMA", NewEWMA(0.5), []float64{4, 3, 4.5, 6.25, 3.625, 3.3125}},
+		{"RollingMin", mustTransformer(NewRollingMin(3)), []float64{4, 2, 2, 2, 1, 1}},
+		{"TimeRollingMin", mustTransformer(NewTimeRollingMin(2 * time.Minute)), []float64{4, 2, 2, 6, 1, 1}},
+		{"RollingMax", mustTransformer(NewRollingMax(3)), []float64{4, 4, 6, 8, 8, 8}},
+		{"TimeRollingMax", mustTransformer(NewTimeRollingMax(2 * time.Minute)), []float64{4, 4, 6, 8, 8, 3}},
+	}
+
+	for _, test := range tests {
+		// apply twice to ensure transformers are reset
+		for n := 0; n < 2; n++ {
+			results, err := Apply(points, test.Transformer)
+			if err != nil {
+				t.Fatalf("Error applying %s: %s", test.Name, err.Error())
+			}
+
+			if len(results) != len(test.Expected) {
+				t.Fatalf("Expected %d results from %s, got %d", len(test.Expected), test.Name, len(results))
+			}
+
+			for i, point := range results {
+				if !point.Timestamp().Equal(points[i].Timestamp()) {
+					t.Errorf("Expected %s result %d at %s, got %s", test.Name, i, points[i].Timestamp(), point.Timestamp())
+				}
+
+				v, err := point.Float64()
+				if err != nil {
+					t.Errorf("Error reading %s result %d: %s", test.Name, i, err.Error())
+				} else if math.Abs(v-test.Expected[i]) > 1e-9 {
+					t.Errorf("Expected %s result %d to be %v, got %v", test.Name, i, test.Expected[i], v)
+				}
+			}
+		}
+	}
+}
+
+func TestTransformersNaN(t *testing.T) {
+	tests := []transformTest{
+		{"MovingAverage", mustTransformer(NewMovingAverage(2)), []float64{4, math.NaN(), 3, 4}},
+		{"EWMA", NewEWMA(0.5), []float64{4, math.NaN(), 3, 4.5}},
+		{"RollingMax", mustTransformer(NewRollingMax(2)), []float64{4, math.NaN(), 4, 6}},
+	}
+
+	for _, test := range tests {
+		results, err := Apply(testPoints(4, math.NaN(), 2, 6), test.Transformer)
+		if err != nil {
+			t.Fatalf("Error applying %s: %s", test.Name, err.Error())
+		}
+
+		for i, point := range results {
+			v, _ := point.Float64()
+			if math.IsNaN(v) != math.IsNaN(test.Expected[i]) || !math.IsNaN(v) && v != test.Expected[i] {
+				t.Errorf("Expected %s result %d to be %v, got %v", test.Name, i, test.Expected[i], v)
+			}
+		}
+	}
+}
+
+func TestWindowSize(t *testing.T) {
+	tests := map[string]func() (Transformer, error){
+		"MovingAverage":     func() (Transformer, error) { return NewMovingAverage(0) },
+		"TimeMovingAverage": func() (Transformer, error) { return NewTimeMovingAverage(0) },
+		"RollingMin":        func() (Transformer, error) { return NewRollingMin(-1) },
+		"TimeRollingMin":    func() (Transformer, error) { return NewTimeRollingMin(-time.Minute) },
+		"RollingMax":        func() (Transformer, error) { return NewRollingMax(0) },
+		"TimeRollingMax":    func() (Transformer, error) { return NewTimeRollingMax(0) },
+	}
+
+	for name, fn := range tests {
+		if _, err := fn(); err != ErrInvalidWindow {
+			t.Errorf("Expected ErrInvalidWindow from %s, got %v", name, err)
+		}
+	}
+}
+
+// mustTransformer returns the given transformer, or panics if it could not be
+// created.
+func mustTransformer(t Transformer, err error) Transformer {
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
@@ -0,0 +1,95 @@
+package mgots
+
+import (
+	"time"
+)
+
+// Point is a data point with a decoded value of type T.
+type Point[T any] struct {
+	Timestamp time.Time
+	Value     T
+}
+
+// TypedCollection wraps a Collection so that all values appended to or read
+// from its series are of type T.
+type TypedCollection[T any] struct {
+	Collection Collection
+}
+
+// NewTypedCollection returns a TypedCollection for values of type T, stored in
+// the given collection.
+func NewTypedCollection[T any](collection Collection) *TypedCollection[T] {
+	return &TypedCollection[T]{
+		Collection: collection,
+	}
+}
+
+func (c *TypedCollection[T]) CreateSeries(seriesId interface{}, startTime time.Time) error {
+	return c.Collection.CreateSeries(seriesId, startTime)
+}
+
+func (c *TypedCollection[T]) Append(seriesId interface{}, timestamp time.Time, value T) error {
+	return c.Collection.Append(seriesId, timestamp, value)
+}
+
+func (c *TypedCollection[T]) Update(seriesId interface{}, value T) error {
+	return c.Collection.Update(seriesId, value)
+}
+
+// Range returns the decoded values of all data points between minTime and
+// maxTime.
+func (c *TypedCollection[T]) Range(seriesId interface{}, minTime time.Time, maxTime time.Time) ([]Point[T], error) {
+	points, err := c.Collection.Range(seriesId, minTime, maxTime)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := decodePoints[T](points)
+	if err != nil {
+		return nil, wrapError("Range", seriesId, err)
+	}
+
+	return results, nil
+}
+
+// Latest returns the most recent data point in a series, or nil if the series
+// contains no data.
+func (c *TypedCollection[T]) Latest(seriesId interface{}) (*Point[T], error) {
+	point, err := c.Collection.Latest(seriesId)
+	if err != nil || point == nil {
+		return nil, err
+	}
+
+	result, err := decodePoint[T](point)
+	if err != nil {
+		return nil, wrapError("Latest", seriesId, err)
+	}
+
+	return result, nil
+}
+
+func decodePoint[T any](point DataPoint) (*Point[T], error) {
+	p := &Point[T]{
+		Timestamp: point.Timestamp(),
+	}
+
+	if err := point.GetValue(&p.Value); err != nil {
+		return nil, newError(err, "Error decoding value at %s", p.Timestamp)
+	}
+
+	return p, nil
+}
+
+func decodePoints[T any](points DataPoints) ([]Point[T], error) {
+	results := make([]Point[T], len(points))
+	for i, point := range points {
+		p, err := decodePoint[T](point)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = *p
+	}
+
+	return results, nil
+}
@@ -0,0 +1,63 @@
+package mgots
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestTypedCollection(t *testing.T) {
+	database := DBConnect()
+	name := "test_typed_collection"
+
+	// Create a typed nonperiodic collection
+	np, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	collection := NewTypedCollection[testData](np)
+
+	// Create a new series in the collection
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	// Add sequencial data to the series
+	entryCount := 100
+	for i := 0; i < entryCount; i++ {
+		timestamp := startTime.Add(time.Duration(i) * time.Minute)
+		err := collection.Append(seriesId, timestamp, testData{i, "A little bit of padding."})
+		if err != nil {
+			t.Errorf(err.Error())
+		}
+	}
+
+	// Validate decoded range values
+	points, err := collection.Range(seriesId, startTime, startTime.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(points) != entryCount {
+		t.Errorf("Expected %d data entries to be returned. Got %d.", entryCount, len(points))
+	}
+
+	for i, point := range points {
+		if point.Value.Sequence != i {
+			t.Errorf("Expected data sequence %d. Got %d.", i, point.Value.Sequence)
+		}
+	}
+
+	// Validate decoded latest value
+	latest, err := collection.Latest(seriesId)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if latest == nil || latest.Value.Sequence != entryCount-1 {
+		t.Errorf("Expected latest sequence %d. Got %v.", entryCount-1, latest)
+	}
+}
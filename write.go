@@ -0,0 +1,165 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"strconv"
+	"time"
+)
+
+// maxWrites is the number of recent write IDs remembered for each series.
+const maxWrites = 100
+
+// Errors
+var ErrWriteConflict = errors.New("The write ID has expired or does not match the entries already appended")
+
+// errWriteApplied is returned internally for an entry which was appended by
+// a previous attempt of the same write.
+var errWriteApplied = errors.New("Entry was already appended by this write")
+
+// errWriteIncomplete is returned internally for an entry whose slot was
+// reserved by a previous attempt of the same write, which failed before
+// writing the entry to a page.
+var errWriteIncomplete = errors.New("Entry was reserved but not written by this write")
+
+// Entry is a value to be appended to a series.
+type Entry struct {
+	Timestamp time.Time
+	Value     interface{}
+}
+
+// writeRecord describes a recent write to a series.
+type writeRecord struct {
+	Id string `bson:"id"` // ID of the write given in AppendOptions
+	N  int    `bson:"n"`  // Number of entries of the write which were appended
+}
+
+// AppendMany appends entries to a series in order, as per Append, stopping
+// at the first entry which cannot be appended.
+//
+// If a write ID is given in options, each entry is recorded on the series
+// cursor in the same update which reserves its slot. Retrying a write with the
+// same ID and entries skips the entries which were already written to a page,
+// and writes an entry whose slot was reserved but not written if it is still
+// the most recent entry of the series, so a write which failed part way
+// through can be safely retried. The most recent 100 write IDs of each series
+// are remembered.
+func (c *NonperiodicCollection) AppendMany(seriesId interface{}, entries []Entry, options AppendOptions) error {
+	for i, entry := range entries {
+		if err := c.appendValue("AppendMany", seriesId, entry.Timestamp, entry.Value, options, i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeChange adds the conditions and operations which record an entry of a
+// write as appended to a query and update of a series cursor. The first entry
+// adds the write to the cursor and each subsequent entry must follow the
+// last entry which was appended.
+func writeChange(selector bson.M, update bson.M, writeId string, entry int) {
+	if entry == 0 {
+		selector["writes.id"] = bson.M{"$ne": writeId}
+		update["$push"] = bson.M{
+			"writes": bson.M{
+				"$each":  []writeRecord{{Id: writeId, N: 1}},
+				"$slice": -maxWrites,
+			},
+		}
+		return
+	}
+
+	inc, ok := update["$inc"].(bson.M)
+	if !ok {
+		inc = bson.M{}
+		update["$inc"] = inc
+	}
+
+	selector["writes"] = bson.M{"$elemMatch": bson.M{"id": writeId, "n": entry}}
+	inc["writes.$.n"] = 1
+}
+
+// recordWrite records an entry of a write as applied without appending it,
+// for duplicates which were overwritten or ignored.
+func (c *NonperiodicCollection) recordWrite(seriesId interface{}, writeId string, entry int) error {
+	if writeId == "" {
+		return nil
+	}
+
+	selector := bson.M{"_id": seriesId}
+	update := bson.M{}
+	writeChange(selector, update, writeId, entry)
+
+	err := c.DBCursorCollection.Update(selector, update)
+	if err != nil && err != mgo.ErrNotFound {
+		return newError(err, "Error recording write on series cursor")
+	}
+
+	return nil
+}
+
+// checkWrite returns errWriteApplied if the given entry of a write was
+// already appended, or ErrWriteConflict if the entries which precede it were
+// not.
+func (c *seriesCursor) checkWrite(writeId string, entry int) error {
+	applied := -1
+	for _, write := range c.Writes {
+		if write.Id == writeId {
+			applied = write.N
+		}
+	}
+
+	switch {
+	case applied > entry:
+		return errWriteApplied
+	case applied < entry && (applied >= 0 || entry > 0):
+		return ErrWriteConflict
+	}
+
+	return nil
+}
+
+// checkWritten returns errWriteApplied if an entry recorded as appended by a
+// write is stored in a page. If it is not, errWriteIncomplete is returned if
+// it is the most recent entry of the series, so its reserved slot can still be
+// written, or nil otherwise.
+func (c *NonperiodicCollection) checkWritten(cursor *seriesCursor, timestamp time.Time) error {
+	// timestamps are stored with millisecond precision
+	var query bson.M
+	latest := cursor.LastValueTime.Equal(timestamp.Truncate(time.Millisecond))
+	if latest {
+		// the slot of the most recent entry is the last reserved slot, unless
+		// its page was never created
+		if cursor.NextSlotId < 0 {
+			return errWriteIncomplete
+		}
+
+		query = bson.M{
+			"_id": cursor.LastPage,
+			"timestamps." + strconv.Itoa(cursor.NextSlotId): timestamp,
+		}
+	} else {
+		query = bson.M{
+			"seriesid":   cursor.SeriesId,
+			"starttime":  bson.M{"$lte": timestamp},
+			"endtime":    bson.M{"$gte": timestamp},
+			"timestamps": timestamp,
+		}
+	}
+
+	n, err := c.DBCollection.Find(query).Count()
+	if err != nil {
+		return newError(err, "Error searching for time series pages")
+	}
+
+	switch {
+	case n > 0:
+		return errWriteApplied
+	case latest:
+		return errWriteIncomplete
+	}
+
+	return nil
+}
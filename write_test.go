@@ -0,0 +1,133 @@
+package mgots
+
+import (
+	"errors"
+	"gopkg.in/mgo.v2/bson"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAppendWriteId(t *testing.T) {
+	database := DBConnect()
+	name := "test_append_write_id"
+
+	// Create a nonperiodic collection
+	collection, err := NewNonperiodicCollection(database, name, testPageSize)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	seriesId := bson.NewObjectId()
+	startTime := time.Now().AddDate(-1, 0, 0).Truncate(time.Hour)
+	err = collection.CreateSeries(seriesId, startTime)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	minute := func(m int) time.Time {
+		return startTime.Add(time.Duration(m) * time.Minute)
+	}
+
+	// Retries of an applied write have no effect
+	for i := 0; i < 3; i++ {
+		err = collection.AppendWithOptions(seriesId, minute(0), 1, AppendOptions{WriteId: "a"})
+		if err != nil {
+			t.Fatalf("Error on attempt %d: %v", i, err)
+		}
+	}
+
+	// Retries of a partially applied write append the remaining entries
+	entries := []Entry{{minute(1), 2}, {minute(2), 3}, {minute(3), 4}}
+	err = collection.AppendMany(seriesId, entries[:2], AppendOptions{WriteId: "b"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i := 0; i < 2; i++ {
+		err = collection.AppendMany(seriesId, entries, AppendOptions{WriteId: "b"})
+		if err != nil {
+			t.Fatalf("Error on attempt %d: %v", i, err)
+		}
+	}
+
+	// Other writes are appended as usual
+	err = collection.AppendWithOptions(seriesId, minute(0), 1, AppendOptions{WriteId: "c"})
+	if !errors.Is(err, ErrTooOld) {
+		t.Errorf("Expected ErrTooOld, got %v", err)
+	}
+
+	err = collection.AppendMany(seriesId, []Entry{{minute(4), 5}}, AppendOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Entries may not follow entries of a write which were not appended
+	err = collection.appendValue("AppendMany", seriesId, minute(5), 6, AppendOptions{WriteId: "d"}, 1)
+	if !errors.Is(err, ErrWriteConflict) || !errors.Is(err, ErrorKindConflict) {
+		t.Errorf("Expected ErrWriteConflict, got %v", err)
+	}
+
+	points, err := collection.Range(seriesId, startTime, minute(10))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(points) != 5 {
+		t.Fatalf("Expected 5 data points, got %d", len(points))
+	}
+
+	for i, point := range points {
+		v, err := point.Float64()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if !point.Timestamp().Equal(minute(i)) || v != float64(i+1) {
+			t.Errorf("Expected %v at %v, got %v at %v", float64(i+1), minute(i), v, point.Timestamp())
+		}
+	}
+
+	stats, err := collection.Stats(seriesId)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if stats.Count != 5 {
+		t.Errorf("Expected 5 entries, got %d", stats.Count)
+	}
+
+	// Retries write an entry whose slot was reserved but not written
+	err = collection.AppendWithOptions(seriesId, minute(5), 6, AppendOptions{WriteId: "e"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Simulate a failure to write the page by clearing the reserved slot
+	var cursor seriesCursor
+	err = database.C(name + cursorSuffix).FindId(seriesId).One(&cursor)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	err = database.C(name).UpdateId(cursor.LastPage, bson.M{
+		"$set": bson.M{"timestamps." + strconv.Itoa(cursor.NextSlotId): time.Time{}},
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	err = collection.AppendWithOptions(seriesId, minute(5), 6, AppendOptions{WriteId: "e"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	points, err = collection.Range(seriesId, minute(5), minute(5))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if len(points) != 1 {
+		t.Fatalf("Expected the retried entry to be written, got %d data points", len(points))
+	}
+}